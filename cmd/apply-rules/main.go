@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"transactions/internal/db"
+)
+
+// apply-rules re-applies the categorisation rules of a user to the transaction history.
+func main() {
+	var userID, prefix string
+	var overwrite bool
+
+	// Parsing command-line arguments
+	flag.StringVar(&userID, "user", "", "ID of the user whose transactions to update")
+	flag.StringVar(&prefix, "ts", "", "Timestamp prefix of the transactions to update, all if empty")
+	flag.BoolVar(&overwrite, "overwrite", false, "Replace existing categories")
+	flag.Parse()
+
+	if userID == "" {
+		fmt.Println("The -user flag is required")
+		os.Exit(2)
+	}
+
+	startTime := time.Now()
+
+	client := db.NewClient()
+	report, err := client.ReapplyRules(context.Background(), db.UserListRequest{
+		UserID:          userID,
+		TimestampPrefix: prefix,
+	}, overwrite)
+	if err != nil {
+		fmt.Println("Failed to apply rules:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Transactions scanned: %d\n", report.Scanned)
+	fmt.Printf("Transactions updated: %d\n", report.Updated)
+	fmt.Printf("Total time taken: %s\n", time.Since(startTime))
+}
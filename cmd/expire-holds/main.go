@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+
+	"transactions/internal/db"
+)
+
+var client *db.Client
+
+func init() {
+	client = db.NewClient()
+}
+
+// isTTLRemove reports whether the record is a deletion made by the DynamoDB TTL process.
+func isTTLRemove(record events.DynamoDBEventRecord) bool {
+	return record.EventName == string(events.DynamoDBOperationTypeRemove) &&
+		record.UserIdentity != nil &&
+		record.UserIdentity.Type == "Service" &&
+		record.UserIdentity.PrincipalID == "dynamodb.amazonaws.com"
+}
+
+// handler handles Holds table stream events and records holds deleted by TTL as expired.
+func handler(ctx context.Context, event events.DynamoDBEvent) error {
+	for _, record := range event.Records {
+		if !isTTLRemove(record) {
+			continue
+		}
+
+		var h db.Hold
+		image := db.AttributesFromStreamImage(record.Change.OldImage)
+		if err := attributevalue.UnmarshalMap(image, &h); err != nil {
+			return fmt.Errorf("failed to decode hold from stream record %s: %w", record.EventID, err)
+		}
+
+		log.Printf("expiring hold %s of user %s", h.ID, h.UserID)
+		if err := client.ExpireHold(ctx, h); err != nil {
+			return fmt.Errorf("failed to expire hold %s: %w", h.ID, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
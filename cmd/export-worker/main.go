@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+
+	"transactions/internal/db"
+	"transactions/internal/export"
+	"transactions/internal/store"
+)
+
+var (
+	client *db.Client
+	worker export.Worker
+)
+
+func init() {
+	client = db.NewClient()
+	accounts, err := export.AccountsFromEnv()
+	if err != nil {
+		log.Fatalf("failed to get account mapping: %s", err)
+	}
+	worker = export.Worker{Pager: client, Store: store.FromEnv(), Accounts: accounts}
+}
+
+// process processes the next chunk of the job and checkpoints it. Every
+// checkpoint modifies the job record, which triggers the next chunk.
+func process(ctx context.Context, job db.ExportJob) error {
+	next, err := worker.Step(ctx, job)
+	if err != nil {
+		log.Printf("ERROR: failed to process export %s: %s", job.ID, err.Error())
+		next = job
+		next.Fail(err)
+	}
+
+	err = client.SaveExportJob(ctx, next, job.Parts)
+	if errors.Is(err, db.ErrConcurrentUpdate) {
+		// stream records may be delivered more than once, the chunk was already checkpointed
+		log.Printf("export %s was checkpointed concurrently", job.ID)
+		return nil
+	}
+	return err
+}
+
+// handler handles Exports table stream events and processes the jobs in progress.
+func handler(ctx context.Context, event events.DynamoDBEvent) error {
+	for _, record := range event.Records {
+		if len(record.Change.NewImage) == 0 {
+			continue
+		}
+
+		var job db.ExportJob
+		if err := attributevalue.UnmarshalMap(
+			db.AttributesFromStreamImage(record.Change.NewImage),
+			&job,
+		); err != nil {
+			return fmt.Errorf("failed to decode export job of stream record %s: %w", record.EventID, err)
+		}
+		if job.IsDone() {
+			continue
+		}
+
+		if err := process(ctx, job); err != nil {
+			return fmt.Errorf("failed to checkpoint export %s: %w", job.ID, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
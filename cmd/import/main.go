@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"transactions/internal/db"
+	"transactions/internal/importer"
+)
+
+// printBalanceCheck prints the balances reported by a statement and the computed closing balance.
+func printBalanceCheck(b importer.BalanceCheck) {
+	fmt.Printf("Statement %s\n", b.Account)
+	if b.Opening != nil {
+		fmt.Printf("  Opening balance: %.2f %s on %s\n", b.Opening.Amount, b.Opening.Currency, b.Opening.Date)
+	}
+	if b.Closing != nil {
+		fmt.Printf("  Closing balance: %.2f %s on %s\n", b.Closing.Amount, b.Closing.Currency, b.Closing.Date)
+	}
+	fmt.Printf("  Computed closing balance: %.2f\n", b.Computed)
+	fmt.Printf("  Difference: %.2f\n", b.Difference)
+}
+
+// import imports a bank statement as transactions of a user.
+func main() {
+	var userID, file, format, profileName string
+	var batchSize int
+	var dryRun bool
+
+	// Parsing command-line arguments
+	flag.StringVar(&userID, "user", "", "ID of the user to import the transactions of")
+	flag.StringVar(&file, "file", "", "Statement file to import, standard input if empty")
+	flag.StringVar(&format, "format", "csv", "Statement format: csv, ofx, qfx, qif, camt.053 or mt940")
+	flag.StringVar(&profileName, "profile", "default", "Built-in CSV profile name or path to a JSON profile file")
+	flag.IntVar(&batchSize, "batch", importer.DefaultBatchSize, "Number of transactions written per batch")
+	flag.BoolVar(&dryRun, "dry-run", false, "Only validate the statement")
+	flag.Parse()
+
+	if userID == "" {
+		fmt.Println("The -user flag is required")
+		os.Exit(2)
+	}
+
+	profile, err := importer.LoadProfile(profileName)
+	if err != nil {
+		fmt.Println("Failed to load profile:", err)
+		os.Exit(2)
+	}
+	parser, err := importer.NewParser(format, profile)
+	if err != nil {
+		fmt.Println("Failed to make parser:", err)
+		os.Exit(2)
+	}
+
+	var r io.Reader = os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Println("Failed to open statement:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	startTime := time.Now()
+
+	entries, balances, err := importer.Parse(parser, r, userID)
+	if err != nil {
+		fmt.Println("Failed to parse statement:", err)
+		os.Exit(1)
+	}
+
+	client := db.NewClient()
+	im := importer.Importer{Writer: client, BatchSize: batchSize, DryRun: dryRun}
+	report, err := im.Import(context.Background(), entries)
+	if err != nil {
+		fmt.Println("Failed to import statement:", err)
+		os.Exit(1)
+	}
+
+	for _, e := range report.Errors {
+		fmt.Printf("Line %d: %s\n", e.Line, e.Error)
+	}
+	for _, d := range report.Duplicates {
+		action := "flagged"
+		if d.Rejected {
+			action = "rejected"
+		}
+		fmt.Printf("Line %d: duplicate of %s/%s, %s\n", d.Line, d.DuplicateOf.UserID, d.DuplicateOf.Timestamp, action)
+	}
+	fmt.Printf("Rows: %d\n", report.Rows)
+	fmt.Printf("Transactions imported: %d\n", report.Imported)
+	fmt.Printf("Rows failed: %d\n", report.Failed)
+	fmt.Printf("Duplicates: %d\n", len(report.Duplicates))
+
+	for _, b := range balances {
+		printBalanceCheck(b)
+	}
+	if len(balances) > 0 {
+		balance, err := client.Balance(context.Background(), userID)
+		if err != nil {
+			fmt.Println("Failed to compute ledger balance:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Ledger balance of %s: %.2f\n", userID, balance.Ledger)
+	}
+	fmt.Printf("Total time taken: %s\n", time.Since(startTime))
+}
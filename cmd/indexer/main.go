@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+
+	"transactions/internal/db"
+)
+
+var client *db.Client
+
+func init() {
+	client = db.NewClient()
+}
+
+// transactionFromImage decodes a transaction from a stream image, nil if there is no image.
+func transactionFromImage(image map[string]events.DynamoDBAttributeValue) (*db.Transaction, error) {
+	if len(image) == 0 {
+		return nil, nil
+	}
+
+	var tr db.Transaction
+	if err := attributevalue.UnmarshalMap(db.AttributesFromStreamImage(image), &tr); err != nil {
+		return nil, err
+	}
+	return &tr, nil
+}
+
+// handler handles Transactions table stream events and keeps the search index up to date.
+func handler(ctx context.Context, event events.DynamoDBEvent) error {
+	for _, record := range event.Records {
+		before, err := transactionFromImage(record.Change.OldImage)
+		if err != nil {
+			return fmt.Errorf("failed to decode old image of stream record %s: %w", record.EventID, err)
+		}
+		after, err := transactionFromImage(record.Change.NewImage)
+		if err != nil {
+			return fmt.Errorf("failed to decode new image of stream record %s: %w", record.EventID, err)
+		}
+
+		if err := client.IndexTransaction(ctx, before, after); err != nil {
+			return fmt.Errorf("failed to index stream record %s: %w", record.EventID, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
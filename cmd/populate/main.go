@@ -34,11 +34,11 @@ func sendPostRequest(url string, payload interface{}, ch chan<- error) {
 	defer resp.Body.Close()
 
 	// Check for successful response
-	if resp.StatusCode == http.StatusOK {
+	if resp.StatusCode == http.StatusCreated {
 		ch <- nil
 	} else {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		ch <- fmt.Errorf("received non-OK status with: %s", string(bodyBytes))
+		ch <- fmt.Errorf("received non-Created status with: %s", string(bodyBytes))
 	}
 }
 
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"transactions/internal/db"
+	"transactions/internal/importer"
+	"transactions/internal/reconcile"
+)
+
+// printReport prints the unmatched items and the totals of a reconciliation.
+func printReport(report reconcile.Report) {
+	fmt.Printf("Reconciliation from %s to %s\n", report.From, report.To)
+	for _, e := range report.Errors {
+		fmt.Printf("Line %d: %s\n", e.Line, e.Error)
+	}
+	for _, m := range report.Matched {
+		if !m.Exact {
+			fmt.Printf("Line %d: fuzzy match of %s %.2f with %s %.2f\n",
+				m.Line, m.Statement.Timestamp, m.Statement.SignedAmount(),
+				m.Transaction.Timestamp, m.Transaction.SignedAmount())
+		}
+	}
+	for _, item := range report.Missing {
+		fmt.Printf("Line %d: missing %s %.2f %s\n",
+			item.Line, item.Transaction.Timestamp, item.Transaction.SignedAmount(), item.Transaction.Description)
+	}
+	for _, t := range report.Unexpected {
+		fmt.Printf("Unexpected %s %.2f %s\n", t.Timestamp, t.SignedAmount(), t.Description)
+	}
+	fmt.Printf("Matched: %d\n", len(report.Matched))
+	fmt.Printf("Missing: %d\n", len(report.Missing))
+	fmt.Printf("Unexpected: %d\n", len(report.Unexpected))
+	fmt.Printf("Statement total: %.2f\n", report.StatementTotal)
+	fmt.Printf("Ledger total: %.2f\n", report.LedgerTotal)
+	fmt.Printf("Difference: %.2f\n", report.Difference)
+	for _, b := range report.Balances {
+		if b.Closing != nil {
+			fmt.Printf("Statement %s closing balance: %.2f, difference: %.2f\n", b.Account, b.Closing.Amount, b.Difference)
+		}
+	}
+}
+
+// reconcile reconciles a bank statement with the stored transactions of a user.
+// It exits with status 3 when the statement and the transactions do not reconcile.
+func main() {
+	var userID, file, format, profileName, from, to string
+	var opts reconcile.Options
+	var asJSON bool
+
+	// Parsing command-line arguments
+	flag.StringVar(&userID, "user", "", "ID of the user to reconcile the transactions of")
+	flag.StringVar(&file, "file", "", "Statement file to reconcile, standard input if empty")
+	flag.StringVar(&format, "format", "csv", "Statement format: csv, ofx, qfx, qif, camt.053 or mt940")
+	flag.StringVar(&profileName, "profile", "default", "Built-in CSV profile name or path to a JSON profile file")
+	flag.StringVar(&from, "from", "", "First date of the range, the first statement date if empty")
+	flag.StringVar(&to, "to", "", "Last date of the range, the last statement date if empty")
+	flag.Float64Var(&opts.AmountTolerance, "amount-tolerance", reconcile.DefaultAmountTolerance, "Largest amount difference of fuzzy matches")
+	flag.IntVar(&opts.DateTolerance, "date-tolerance", reconcile.DefaultDateTolerance, "Largest number of days between fuzzy matches")
+	flag.BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	flag.Parse()
+
+	if userID == "" {
+		fmt.Println("The -user flag is required")
+		os.Exit(2)
+	}
+
+	profile, err := importer.LoadProfile(profileName)
+	if err != nil {
+		fmt.Println("Failed to load profile:", err)
+		os.Exit(2)
+	}
+	parser, err := importer.NewParser(format, profile)
+	if err != nil {
+		fmt.Println("Failed to make parser:", err)
+		os.Exit(2)
+	}
+
+	var r io.Reader = os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Println("Failed to open statement:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	startTime := time.Now()
+
+	entries, balances, err := importer.Parse(parser, r, userID)
+	if err != nil {
+		fmt.Println("Failed to parse statement:", err)
+		os.Exit(1)
+	}
+
+	rec := reconcile.Reconciler{Lister: db.NewClient(), Options: opts}
+	report, err := rec.Reconcile(context.Background(), userID, from, to, entries)
+	if err != nil {
+		fmt.Println("Failed to reconcile statement:", err)
+		os.Exit(1)
+	}
+	report.Balances = balances
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Println("Failed to encode report:", err)
+			os.Exit(1)
+		}
+	} else {
+		printReport(report)
+		fmt.Printf("Total time taken: %s\n", time.Since(startTime))
+	}
+	if !report.Reconciled() {
+		os.Exit(3)
+	}
+}
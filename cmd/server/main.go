@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"transactions/internal/api"
+)
+
+func main() {
+	var addr string
+	var shutdownTimeout time.Duration
+
+	flag.StringVar(&addr, "addr", ":8080", "Address to listen on")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 10*time.Second, "Time to finish the requests in flight on shutdown")
+	flag.Parse()
+
+	if url := os.Getenv("LOCAL_DYNAMODB_URL"); url != "" {
+		log.Printf("using DynamoDB at %s", url)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           api.HTTPHandler{Handler: api.Handler},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		log.Fatalf("failed to serve: %s", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Printf("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("failed to shut down: %s", err)
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("failed to serve: %s", err)
+	}
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ErrUnknownEvent is returned for payloads of an unknown event source.
+var ErrUnknownEvent = errors.New("unknown event")
+
+// Event sources the API is invoked from.
+const (
+	EventAPIGateway   = "apigateway"   // API Gateway REST API, or HTTP API with the 1.0 payload
+	EventHTTPAPI      = "httpapi"      // API Gateway HTTP API with the 2.0 payload
+	EventALB          = "alb"          // Application Load Balancer target group
+	EventFunctionURL  = "functionurl"  // Lambda function URL
+	functionURLDomain = ".lambda-url." // domain of function URLs, e.g. <url-id>.lambda-url.<region>.on.aws
+)
+
+// DetectEvent returns the source of an event payload.
+func DetectEvent(payload []byte) (string, error) {
+	var probe struct {
+		Version        string `json:"version"`
+		HTTPMethod     string `json:"httpMethod"`
+		RequestContext struct {
+			ELB        json.RawMessage `json:"elb"`
+			HTTP       json.RawMessage `json:"http"`
+			DomainName string          `json:"domainName"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return "", fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	switch {
+	case probe.RequestContext.ELB != nil:
+		return EventALB, nil
+	case probe.Version == "2.0" && strings.Contains(probe.RequestContext.DomainName, functionURLDomain):
+		return EventFunctionURL, nil
+	case probe.Version == "2.0" && probe.RequestContext.HTTP != nil:
+		return EventHTTPAPI, nil
+	case probe.HTTPMethod != "":
+		return EventAPIGateway, nil
+	}
+	return "", ErrUnknownEvent
+}
+
+// LambdaHandler returns a Lambda handler serving the events of API Gateway REST
+// and HTTP APIs, Application Load Balancers and function URLs with the handler.
+// Events are translated into API Gateway proxy requests, detected from their
+// payload, and the responses into the responses of their source.
+func LambdaHandler(h HandlerFunc) func(context.Context, json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		source, err := DetectEvent(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		switch source {
+		case EventHTTPAPI:
+			var e events.APIGatewayV2HTTPRequest
+			if err := json.Unmarshal(payload, &e); err != nil {
+				return nil, err
+			}
+			res, err := h(ctx, fromHTTPAPI(e))
+			if err != nil {
+				return nil, err
+			}
+			return toHTTPAPI(res), nil
+		case EventFunctionURL:
+			var e events.LambdaFunctionURLRequest
+			if err := json.Unmarshal(payload, &e); err != nil {
+				return nil, err
+			}
+			res, err := h(ctx, fromFunctionURL(e))
+			if err != nil {
+				return nil, err
+			}
+			return toFunctionURL(res), nil
+		case EventALB:
+			var e events.ALBTargetGroupRequest
+			if err := json.Unmarshal(payload, &e); err != nil {
+				return nil, err
+			}
+			res, err := h(ctx, fromALB(e))
+			if err != nil {
+				return nil, err
+			}
+			return toALB(res, e.MultiValueHeaders != nil), nil
+		}
+
+		var e events.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return h(ctx, e)
+	}
+}
+
+// fromHTTPAPI translates an HTTP API request into a proxy request. The resource
+// is the path of the route key, and the stage is removed from the path.
+func fromHTTPAPI(e events.APIGatewayV2HTTPRequest) events.APIGatewayProxyRequest {
+	req := fromPayloadV2(
+		e.RawPath,
+		e.RawQueryString,
+		e.QueryStringParameters,
+		e.Headers,
+		e.Cookies,
+		e.Body,
+		e.IsBase64Encoded,
+	)
+	req.HTTPMethod = e.RequestContext.HTTP.Method
+	if i := strings.IndexByte(e.RouteKey, ' '); i >= 0 {
+		req.Resource = e.RouteKey[i+1:]
+	}
+	if stage := e.RequestContext.Stage; stage != "" && stage != "$default" {
+		req.Path = strings.TrimPrefix(req.Path, "/"+stage)
+	}
+	req.PathParameters = e.PathParameters
+	req.StageVariables = e.StageVariables
+	req.RequestContext = events.APIGatewayProxyRequestContext{
+		AccountID:  e.RequestContext.AccountID,
+		RequestID:  e.RequestContext.RequestID,
+		APIID:      e.RequestContext.APIID,
+		DomainName: e.RequestContext.DomainName,
+		Stage:      e.RequestContext.Stage,
+		HTTPMethod: req.HTTPMethod,
+		Path:       e.RawPath,
+		Identity: events.APIGatewayRequestIdentity{
+			SourceIP:  e.RequestContext.HTTP.SourceIP,
+			UserAgent: e.RequestContext.HTTP.UserAgent,
+		},
+	}
+	return req
+}
+
+// fromFunctionURL translates a function URL request into a proxy request.
+func fromFunctionURL(e events.LambdaFunctionURLRequest) events.APIGatewayProxyRequest {
+	req := fromPayloadV2(
+		e.RawPath,
+		e.RawQueryString,
+		e.QueryStringParameters,
+		e.Headers,
+		e.Cookies,
+		e.Body,
+		e.IsBase64Encoded,
+	)
+	req.HTTPMethod = e.RequestContext.HTTP.Method
+	req.RequestContext = events.APIGatewayProxyRequestContext{
+		AccountID:  e.RequestContext.AccountID,
+		RequestID:  e.RequestContext.RequestID,
+		APIID:      e.RequestContext.APIID,
+		DomainName: e.RequestContext.DomainName,
+		HTTPMethod: req.HTTPMethod,
+		Path:       e.RawPath,
+		Identity: events.APIGatewayRequestIdentity{
+			SourceIP:  e.RequestContext.HTTP.SourceIP,
+			UserAgent: e.RequestContext.HTTP.UserAgent,
+		},
+	}
+	return req
+}
+
+// fromPayloadV2 translates the fields shared by the 2.0 payloads of HTTP APIs
+// and function URLs. Query parameters are parsed from the raw query string,
+// as the query parameters join repeated values with commas, and cookies are
+// given back their header.
+func fromPayloadV2(
+	rawPath, rawQuery string,
+	query, headers map[string]string,
+	cookies []string,
+	body string,
+	isBase64Encoded bool,
+) events.APIGatewayProxyRequest {
+	req := events.APIGatewayProxyRequest{
+		Path:                  rawPath,
+		Headers:               make(map[string]string, len(headers)+1),
+		QueryStringParameters: query,
+		Body:                  body,
+		IsBase64Encoded:       isBase64Encoded,
+	}
+	for k, v := range headers {
+		req.Headers[k] = v
+	}
+	if len(cookies) > 0 {
+		req.Headers["cookie"] = strings.Join(cookies, "; ")
+	}
+
+	if values, err := url.ParseQuery(rawQuery); err == nil && len(values) > 0 {
+		req.MultiValueQueryStringParameters = values
+		req.QueryStringParameters = make(map[string]string, len(values))
+		for k, v := range values {
+			req.QueryStringParameters[k] = v[0]
+		}
+	}
+	return req
+}
+
+// fromALB translates a load balancer request into a proxy request. The load
+// balancer passes the query parameters as they were sent, they are decoded.
+func fromALB(e events.ALBTargetGroupRequest) events.APIGatewayProxyRequest {
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod:        e.HTTPMethod,
+		Path:              e.Path,
+		Headers:           e.Headers,
+		MultiValueHeaders: e.MultiValueHeaders,
+		Body:              e.Body,
+		IsBase64Encoded:   e.IsBase64Encoded,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod: e.HTTPMethod,
+			Path:       e.Path,
+		},
+	}
+
+	if e.QueryStringParameters != nil {
+		req.QueryStringParameters = make(map[string]string, len(e.QueryStringParameters))
+		for k, v := range e.QueryStringParameters {
+			req.QueryStringParameters[unescapeQuery(k)] = unescapeQuery(v)
+		}
+	}
+	if e.MultiValueQueryStringParameters != nil {
+		req.QueryStringParameters = make(map[string]string, len(e.MultiValueQueryStringParameters))
+		req.MultiValueQueryStringParameters = make(map[string][]string, len(e.MultiValueQueryStringParameters))
+		for k, vs := range e.MultiValueQueryStringParameters {
+			k = unescapeQuery(k)
+			for _, v := range vs {
+				req.MultiValueQueryStringParameters[k] = append(req.MultiValueQueryStringParameters[k], unescapeQuery(v))
+			}
+			if len(vs) > 0 {
+				req.QueryStringParameters[k] = req.MultiValueQueryStringParameters[k][0]
+			}
+		}
+	}
+
+	// the load balancer does not give the client IP address in the request context
+	if forwarded := header(req, "X-Forwarded-For"); forwarded != "" {
+		req.RequestContext.Identity.SourceIP = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	req.RequestContext.Identity.UserAgent = header(req, "User-Agent")
+	return req
+}
+
+// unescapeQuery decodes an escaped query parameter.
+func unescapeQuery(s string) string {
+	if u, err := url.QueryUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// toHTTPAPI translates a proxy response into an HTTP API response.
+func toHTTPAPI(res events.APIGatewayProxyResponse) events.APIGatewayV2HTTPResponse {
+	headers, cookies := joinHeaders(res)
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode:      res.StatusCode,
+		Headers:         headers,
+		Body:            res.Body,
+		IsBase64Encoded: res.IsBase64Encoded,
+		Cookies:         cookies,
+	}
+}
+
+// toFunctionURL translates a proxy response into a function URL response.
+func toFunctionURL(res events.APIGatewayProxyResponse) events.LambdaFunctionURLResponse {
+	headers, cookies := joinHeaders(res)
+	return events.LambdaFunctionURLResponse{
+		StatusCode:      res.StatusCode,
+		Headers:         headers,
+		Body:            res.Body,
+		IsBase64Encoded: res.IsBase64Encoded,
+		Cookies:         cookies,
+	}
+}
+
+// joinHeaders returns the headers of a response with the values of repeated headers
+// joined with commas, as the 2.0 payloads have no multi-value headers, and the cookies.
+func joinHeaders(res events.APIGatewayProxyResponse) (map[string]string, []string) {
+	headers := make(map[string]string, len(res.Headers)+len(res.MultiValueHeaders))
+	var cookies []string
+	for k, vs := range mergeHeaders(res) {
+		if http.CanonicalHeaderKey(k) == "Set-Cookie" {
+			cookies = append(cookies, vs...)
+			continue
+		}
+		headers[k] = strings.Join(vs, ",")
+	}
+	return headers, cookies
+}
+
+// toALB translates a proxy response into a load balancer response, with multi-value
+// headers when they are enabled on the target group, i.e. the request has them.
+func toALB(res events.APIGatewayProxyResponse, multiValue bool) events.ALBTargetGroupResponse {
+	alb := events.ALBTargetGroupResponse{
+		StatusCode:        res.StatusCode,
+		StatusDescription: fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)),
+		Body:              res.Body,
+		IsBase64Encoded:   res.IsBase64Encoded,
+	}
+	headers := mergeHeaders(res)
+	if multiValue {
+		alb.MultiValueHeaders = headers
+		return alb
+	}
+	alb.Headers = make(map[string]string, len(headers))
+	for k, vs := range headers {
+		alb.Headers[k] = vs[len(vs)-1]
+	}
+	return alb
+}
+
+// mergeHeaders returns the single and multi-value headers of a response,
+// the multi-value headers adding to the single value ones.
+func mergeHeaders(res events.APIGatewayProxyResponse) map[string][]string {
+	headers := make(map[string][]string, len(res.Headers)+len(res.MultiValueHeaders))
+	for k, v := range res.Headers {
+		headers[k] = []string{v}
+	}
+	for k, vs := range res.MultiValueHeaders {
+		headers[k] = append(headers[k], vs...)
+	}
+	return headers
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func readEvent(t *testing.T, path string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDetectEvent(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"../../events/list.json", EventAPIGateway},
+		{"testdata/http-api.json", EventHTTPAPI},
+		{"testdata/function-url.json", EventFunctionURL},
+		{"testdata/alb.json", EventALB},
+		{"testdata/alb-multivalue.json", EventALB},
+	}
+	for _, tt := range tests {
+		got, err := DetectEvent(readEvent(t, tt.path))
+		if err != nil {
+			t.Errorf("DetectEvent(%s) unexpected error: %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("DetectEvent(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := DetectEvent([]byte(`{"Records": []}`)); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("expected ErrUnknownEvent, got %v", err)
+	}
+}
+
+// recordingHandler returns a handler recording the requests it handles,
+// responding with repeated headers.
+func recordingHandler(got *events.APIGatewayProxyRequest) HandlerFunc {
+	h := func(
+		_ context.Context,
+		req events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		*got = req
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			MultiValueHeaders: map[string][]string{
+				"Vary":       {"Accept", "Accept-Encoding"},
+				"Set-Cookie": {"a=1", "b=2"},
+			},
+			Body: `{}`,
+		}, nil
+	}
+
+	r := NewRouter()
+	r.Handle(http.MethodGet, "/transactions/{user_id}/{ts}", h)
+	r.Handle(http.MethodGet, "/users/{user_id}/transactions/{ts}", h)
+	r.Handle(http.MethodPost, "/users/{user_id}/rules/apply", h)
+	return r.Serve
+}
+
+func TestLambdaHandler_APIGateway(t *testing.T) {
+	var got events.APIGatewayProxyRequest
+	out, err := LambdaHandler(recordingHandler(&got))(context.Background(), readEvent(t, "../../events/list.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok := out.(events.APIGatewayProxyResponse)
+	if !ok || res.StatusCode != http.StatusOK || len(res.MultiValueHeaders["Set-Cookie"]) != 2 {
+		t.Errorf("unexpected response %#v", out)
+	}
+	if got.Resource != "/transactions/{user_id}/{ts}" || got.PathParameters["user_id"] != "nick" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
+
+func TestLambdaHandler_HTTPAPI(t *testing.T) {
+	var got events.APIGatewayProxyRequest
+	out, err := LambdaHandler(recordingHandler(&got))(context.Background(), readEvent(t, "testdata/http-api.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok := out.(events.APIGatewayV2HTTPResponse)
+	if !ok {
+		t.Fatalf("unexpected response type %T", out)
+	}
+	if res.StatusCode != http.StatusOK || res.Headers["Vary"] != "Accept,Accept-Encoding" ||
+		res.Headers["Content-Type"] != "application/json" || res.Headers["Set-Cookie"] != "" {
+		t.Errorf("unexpected response %+v", res)
+	}
+	if !reflect.DeepEqual(res.Cookies, []string{"a=1", "b=2"}) {
+		t.Errorf("unexpected cookies %v", res.Cookies)
+	}
+
+	if got.HTTPMethod != http.MethodGet ||
+		got.Resource != "/users/{user_id}/transactions/{ts}" ||
+		got.Path != "/users/john/transactions/2024-01-02T10:00:00Z" ||
+		got.PathParameters["ts"] != "2024-01-02T10:00:00Z" ||
+		!reflect.DeepEqual(got.MultiValueQueryStringParameters["tag"], []string{"food", "work"}) ||
+		got.QueryStringParameters["tag"] != "food" ||
+		header(got, "Cookie") != "session=abc; theme=dark" ||
+		got.RequestContext.RequestID != "LV7fzho-PHcEJPw=" ||
+		got.RequestContext.Identity.SourceIP != "1.2.3.4" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
+
+func TestLambdaHandler_FunctionURL(t *testing.T) {
+	var got events.APIGatewayProxyRequest
+	out, err := LambdaHandler(recordingHandler(&got))(context.Background(), readEvent(t, "testdata/function-url.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok := out.(events.LambdaFunctionURLResponse)
+	if !ok {
+		t.Fatalf("unexpected response type %T", out)
+	}
+	if res.StatusCode != http.StatusOK || len(res.Cookies) != 2 || res.Headers["Vary"] != "Accept,Accept-Encoding" {
+		t.Errorf("unexpected response %+v", res)
+	}
+
+	if got.HTTPMethod != http.MethodPost ||
+		got.Resource != "/users/{user_id}/rules/apply" ||
+		got.PathParameters["user_id"] != "john" ||
+		got.QueryStringParameters["dry_run"] != "true" ||
+		!got.IsBase64Encoded || got.Body != "eyJmcm9tIjoiMjAyNCJ9" ||
+		got.RequestContext.Identity.SourceIP != "5.6.7.8" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
+
+func TestLambdaHandler_ALB(t *testing.T) {
+	var got events.APIGatewayProxyRequest
+	out, err := LambdaHandler(recordingHandler(&got))(context.Background(), readEvent(t, "testdata/alb.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok := out.(events.ALBTargetGroupResponse)
+	if !ok {
+		t.Fatalf("unexpected response type %T", out)
+	}
+	if res.StatusCode != http.StatusOK || res.StatusDescription != "200 OK" ||
+		res.MultiValueHeaders != nil || res.Headers["Content-Type"] != "application/json" {
+		t.Errorf("unexpected response %+v", res)
+	}
+
+	if got.Resource != "/users/{user_id}/transactions/{ts}" ||
+		got.PathParameters["ts"] != "2024-01-02T10:00:00Z" ||
+		got.QueryStringParameters["q"] != "coffee shop" ||
+		got.RequestContext.Identity.SourceIP != "72.21.198.67" ||
+		got.RequestContext.Identity.UserAgent != "curl/8.4.0" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
+
+func TestLambdaHandler_ALBMultiValue(t *testing.T) {
+	var got events.APIGatewayProxyRequest
+	out, err := LambdaHandler(recordingHandler(&got))(context.Background(), readEvent(t, "testdata/alb-multivalue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok := out.(events.ALBTargetGroupResponse)
+	if !ok {
+		t.Fatalf("unexpected response type %T", out)
+	}
+	if res.Headers != nil ||
+		!reflect.DeepEqual(res.MultiValueHeaders["Set-Cookie"], []string{"a=1", "b=2"}) ||
+		!reflect.DeepEqual(res.MultiValueHeaders["Content-Type"], []string{"application/json"}) {
+		t.Errorf("unexpected response %+v", res)
+	}
+
+	if !reflect.DeepEqual(got.MultiValueQueryStringParameters["tag"], []string{"food", "work/lunch"}) ||
+		got.QueryStringParameters["tag"] != "food" ||
+		header(got, "Accept") != "application/json" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
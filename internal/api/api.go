@@ -0,0 +1,226 @@
+// Package api implements the HTTP API of transactions on API Gateway proxy events.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+)
+
+const (
+	INSERT_TIMEOUT = 10 * time.Second
+	LIST_TIMEOUT   = 10 * time.Second
+)
+
+// CursorHeader is the header of list responses with the cursor of the next page.
+const CursorHeader = "X-Cursor"
+
+var client *db.Client
+
+func init() {
+	client = db.NewClient()
+}
+
+// handleError handles errors responding with the status code of their kind.
+func handleError(format string, args ...interface{}) (events.APIGatewayProxyResponse, error) {
+	err := fmt.Errorf(format, args...)
+	return handleProblem(errorStatus(err), err)
+}
+
+// handleErrorCode handles errors responding with the given status code.
+func handleErrorCode(
+	code int,
+	format string,
+	args ...interface{},
+) (events.APIGatewayProxyResponse, error) {
+	return handleProblem(code, fmt.Errorf(format, args...))
+}
+
+// handleOK handles successful requests.
+func handleOK(body interface{}) (events.APIGatewayProxyResponse, error) {
+	return handleJSON(http.StatusOK, body)
+}
+
+// handleJSON handles successful requests responding with the given status code.
+func handleJSON(code int, body interface{}) (events.APIGatewayProxyResponse, error) {
+	json, err := json.Marshal(body)
+	if err != nil {
+		return handleError("failed to convert response body to JSON: %w", err)
+	}
+	return events.APIGatewayProxyResponse{
+		Body:       string(json),
+		StatusCode: code,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+	}, nil
+}
+
+// handleCreate handles POST /transactions requests, responding with 201 Created
+// and the URL of the transaction in the Location header.
+func handleCreate(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	var tr db.Transaction
+
+	dec := json.NewDecoder(strings.NewReader(request.Body))
+	if err := dec.Decode(&tr); err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, INSERT_TIMEOUT)
+	defer cancel()
+	if err := client.Create(ctx, &tr); err != nil {
+		return handleError("failed to create record: %w", err)
+	}
+
+	res, err := handleJSON(http.StatusCreated, mapper(request).Item(tr))
+	if res.StatusCode == http.StatusCreated {
+		setHeader(&res, "Location", transactionURL(request, tr.PK()))
+	}
+	return res, err
+}
+
+// handleList handles GET /transactions/{user_id}/{ts} requests.
+// ts is a timestamp prefix in iso microseconds format
+func handleList(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	m := mapper(req)
+	listReq, err := m.ListRequest(req)
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to parse request: %w", err)
+	}
+
+	trs, err := client.Query(ctx, listReq)
+	if err != nil {
+		return handleError("failed to query records: %w", err)
+	}
+
+	res, err := handleEncoded(req, m.List(req, trs))
+	if res.StatusCode != http.StatusOK {
+		return res, err
+	}
+	setHeader(&res, "Link", linkHeader(pageLinks(req, trs.Cursor, m.CursorParam())))
+	if trs.Cursor != "" {
+		// the cursor is not in the body of CSV and NDJSON responses
+		setHeader(&res, CursorHeader, trs.Cursor)
+	}
+	return res, err
+}
+
+// handleStats handles GET /users/{user_id}/stats/{ts} requests.
+// It accepts the filter query parameters of the list request.
+func handleStats(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	listReq, err := db.UserListRequestFromAPIGatewayProxyRequest(req)
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to parse request: %w", err)
+	}
+
+	stats, err := client.CategoryStats(ctx, listReq)
+	if err != nil {
+		return handleError("failed to compute stats: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(stats))
+}
+
+// transactionPK returns the primary key of the transaction addressed by the request path.
+func transactionPK(req events.APIGatewayProxyRequest) db.TransactionPK {
+	return db.TransactionPK{
+		UserID:    req.PathParameters["user_id"],
+		Timestamp: req.PathParameters["ts"],
+	}
+}
+
+// handleGet handles GET /users/{user_id}/transactions/{ts} requests.
+// The response includes the refunds of the transaction.
+func handleGet(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	tr, err := client.GetDetails(ctx, transactionPK(req))
+	if err != nil {
+		return handleError("failed to get record: %w", err)
+	}
+
+	return handleEncoded(req, mapper(req).Item(tr))
+}
+
+// router routes the requests of the API Gateway resources of the function.
+var router = newRouter()
+
+// newRouter returns the router of the API, with the middleware shared by all routes.
+func newRouter() *Router {
+	r := NewRouter(
+		Recover,
+		Logging,
+		Metrics(os.Stdout),
+		APIKeyAuth(apiKeysFromEnv()),
+		Versioning,
+		Body(maxBodySizeFromEnv()),
+		Compress(CompressMinSize),
+		Timeout(REQUEST_TIMEOUT, TIMEOUT_MARGIN),
+	)
+
+	// routes are served unversioned and under the prefix of each version
+	handle := func(method, template string, h HandlerFunc) {
+		r.Handle(method, template, h)
+		for v := range mappers {
+			r.Handle(method, "/"+v+template, h)
+		}
+	}
+
+	handle(http.MethodGet, "/transactions/{user_id}/{ts}", ETag(handleList))
+	handle(http.MethodPost, "/transactions", handleCreate)
+	handle(http.MethodGet, "/users/{user_id}/transactions/{ts}", ETag(handleGet))
+	handle(http.MethodPost, "/users/{user_id}/transactions/{ts}/status", handleTransition)
+	handle(http.MethodPost, "/users/{user_id}/transactions/{ts}/refunds", handleRefund)
+	handle(http.MethodGet, "/users/{user_id}/rules", handleListRules)
+	handle(http.MethodPost, "/users/{user_id}/rules", handleCreateRule)
+	handle(http.MethodPut, "/users/{user_id}/rules/{rule_id}", handleUpdateRule)
+	handle(http.MethodDelete, "/users/{user_id}/rules/{rule_id}", handleDeleteRule)
+	handle(http.MethodPost, "/users/{user_id}/rules/apply", handleApplyRules)
+	handle(http.MethodPost, "/users/{user_id}/imports", handleImport)
+	handle(http.MethodPost, "/users/{user_id}/reconciliations", handleReconcile)
+	handle(http.MethodPost, "/exports", handleCreateExport)
+	handle(http.MethodGet, "/exports/{export_id}", handleGetExport)
+	handle(http.MethodGet, "/users/{user_id}/export", handleExport)
+	handle(http.MethodGet, "/users/{user_id}/search", handleSearch)
+	handle(http.MethodGet, "/users/{user_id}/stats/{ts}", handleStats)
+	handle(http.MethodGet, "/users/{user_id}/balance", handleBalance)
+	handle(http.MethodGet, "/users/{user_id}/holds", handleListHolds)
+	handle(http.MethodPost, "/users/{user_id}/holds", handleCreateHold)
+	handle(http.MethodGet, "/users/{user_id}/holds/{hold_id}", handleGetHold)
+	handle(http.MethodPost, "/users/{user_id}/holds/{hold_id}/capture", handleCaptureHold)
+	handle(http.MethodPost, "/users/{user_id}/holds/{hold_id}/release", handleReleaseHold)
+	return r
+}
+
+// Handler handles the API Gateway proxy requests of the API.
+func Handler(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	return router.Serve(ctx, request)
+}
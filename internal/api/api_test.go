@@ -0,0 +1,291 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+	"transactions/pkg/test"
+)
+
+// dynamoDBURL is the URL of the DynamoDB Local the handler tests run against.
+// Without it the tests needing DynamoDB are skipped, and the others still run.
+var dynamoDBURL = os.Getenv("LOCAL_DYNAMODB_URL")
+
+func cleanUp() error {
+	client := db.NewClient()
+	return client.DeleteAll(context.Background())
+}
+
+func TestMain(m *testing.M) {
+	if dynamoDBURL == "" {
+		os.Exit(m.Run())
+	}
+	if err := cleanUp(); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	if err := cleanUp(); err != nil {
+		panic(err)
+	}
+	os.Exit(code)
+}
+
+func MustMarshalJSON(t *testing.T, i interface{}) string {
+	b, err := json.Marshal(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestHandler(t *testing.T) {
+	if dynamoDBURL == "" {
+		t.Skip("LOCAL_DYNAMODB_URL is not set")
+	}
+	tr := test.TransactionFactory.MustCreate().(*db.Transaction)
+
+	// b, _ := json.Marshal(db.ListResponse{Items: []db.Transaction{*tr}})
+
+	testCases := []struct {
+		name           string
+		request        events.APIGatewayProxyRequest
+		expectedBody   string
+		expectedError  error
+		expectedStatus int
+		// expectedHeaders are headers the response must have, among others
+		expectedHeaders map[string]string
+	}{
+		{
+			name: "create invalid transaction without amount",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod: "POST",
+				Resource:   "/transactions",
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Identity: events.APIGatewayRequestIdentity{
+						SourceIP: "",
+					},
+				},
+				Body: `{"user_id":"john","id":"a","operation_type":"credit","origin":"web"}`,
+			},
+			expectedBody: MustMarshalJSON(t, Problem{
+				Type:   "about:blank",
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "failed to create record: amount is required",
+				Errors: []db.FieldError{{Field: "amount", Rule: "required", Message: "is required"}},
+			}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  nil,
+		},
+		{
+			name: "create transaction",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod: "POST",
+				Resource:   "/transactions",
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Identity: events.APIGatewayRequestIdentity{
+						SourceIP: "",
+					},
+				},
+				Body: MustMarshalJSON(t, tr),
+			},
+			expectedBody:   MustMarshalJSON(t, tr),
+			expectedStatus: http.StatusCreated,
+			expectedHeaders: map[string]string{
+				"Location": "/users/" + url.PathEscape(tr.UserID) + "/transactions/" + url.PathEscape(tr.Timestamp),
+			},
+			expectedError: nil,
+		},
+		{
+			name: "list transactions not specifying partition and sort keys",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod: "GET",
+				Resource:   "/transactions/{user_id}/{ts}",
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Identity: events.APIGatewayRequestIdentity{
+						SourceIP: "",
+					},
+				},
+			},
+			expectedBody: MustMarshalJSON(t, Problem{
+				Type:   "about:blank",
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "failed to query records: user_id is required, timestamp_prefix is required without from, to",
+				Errors: []db.FieldError{
+					{Field: "user_id", Rule: "required", Message: "is required"},
+					{Field: "timestamp_prefix", Rule: "required_without_all", Message: "is required without from, to"},
+				},
+			}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  nil,
+		},
+		{
+			name: "list transactions",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod: "GET",
+				Resource:   "/transactions/{user_id}/{ts}",
+				PathParameters: map[string]string{
+					"user_id": tr.UserID,
+					"ts":      tr.Timestamp,
+				},
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Identity: events.APIGatewayRequestIdentity{
+						SourceIP: "",
+					},
+				},
+			},
+			expectedBody: MustMarshalJSON(t, ListResponseV1{
+				ListResponse: db.ListResponse{Items: []db.Transaction{*tr}},
+				Links: Links{
+					Self: "/transactions/" + url.PathEscape(tr.UserID) + "/" + url.PathEscape(tr.Timestamp),
+				},
+			}),
+			expectedStatus: http.StatusOK,
+			expectedError:  nil,
+		},
+		{
+			name: "get transaction",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod: "GET",
+				Resource:   "/users/{user_id}/transactions/{ts}",
+				PathParameters: map[string]string{
+					"user_id": tr.UserID,
+					"ts":      tr.Timestamp,
+				},
+			},
+			expectedBody:   MustMarshalJSON(t, tr),
+			expectedStatus: http.StatusOK,
+			expectedError:  nil,
+		},
+		{
+			name: "export transactions without range",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod:            "GET",
+				Resource:              "/users/{user_id}/export",
+				PathParameters:        map[string]string{"user_id": tr.UserID},
+				QueryStringParameters: map[string]string{"format": "ndjson"},
+			},
+			expectedBody:   MustMarshalJSON(t, tr) + "\n",
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Content-Type": "application/x-ndjson",
+			},
+			expectedError: nil,
+		},
+		{
+			name: "illegal status transition",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod: "POST",
+				Resource:   "/users/{user_id}/transactions/{ts}/status",
+				PathParameters: map[string]string{
+					"user_id": tr.UserID,
+					"ts":      tr.Timestamp,
+				},
+				Body: `{"status":"pending","changed_by":"ops"}`,
+			},
+			expectedBody: MustMarshalJSON(t, Problem{
+				Type:   "about:blank",
+				Title:  "Conflict",
+				Status: http.StatusConflict,
+				Detail: "failed to change status: illegal status transition from posted to pending",
+			}),
+			expectedStatus: http.StatusConflict,
+			expectedError:  nil,
+		},
+		{
+			name: "refund of zero amount",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod: "POST",
+				Resource:   "/users/{user_id}/transactions/{ts}/refunds",
+				PathParameters: map[string]string{
+					"user_id": tr.UserID,
+					"ts":      tr.Timestamp,
+				},
+				Body: `{"amount":0}`,
+			},
+			expectedBody: MustMarshalJSON(t, Problem{
+				Type:   "about:blank",
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "failed to refund: refund amount must be positive: 0",
+			}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  nil,
+		},
+		{
+			name: "refund of negative amount",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod: "POST",
+				Resource:   "/users/{user_id}/transactions/{ts}/refunds",
+				PathParameters: map[string]string{
+					"user_id": tr.UserID,
+					"ts":      tr.Timestamp,
+				},
+				Body: `{"amount":-5}`,
+			},
+			expectedBody: MustMarshalJSON(t, Problem{
+				Type:   "about:blank",
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "failed to refund: refund amount must be positive: -5",
+			}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  nil,
+		},
+		{
+			name: "capture of negative amount",
+			request: events.APIGatewayProxyRequest{
+				HTTPMethod: "POST",
+				Resource:   "/users/{user_id}/holds/{hold_id}/capture",
+				PathParameters: map[string]string{
+					"user_id": tr.UserID,
+					"hold_id": "h1",
+				},
+				Body: `{"amount":-5}`,
+			},
+			expectedBody: MustMarshalJSON(t, Problem{
+				Type:   "about:blank",
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "failed to capture hold: capture amount must not be negative: -5",
+			}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, err := Handler(context.Background(), testCase.request)
+			if err != testCase.expectedError {
+				t.Errorf("Expected error %v, but got %v", testCase.expectedError, err)
+			}
+
+			if response.Body != testCase.expectedBody {
+				t.Errorf("Expected response %v, but got %v", testCase.expectedBody, response.Body)
+			}
+
+			if response.StatusCode != testCase.expectedStatus {
+				t.Errorf(
+					"Expected status code %v, but got %v",
+					testCase.expectedStatus,
+					response.StatusCode,
+				)
+			}
+
+			for name, value := range testCase.expectedHeaders {
+				if response.Headers[name] != value {
+					t.Errorf("Expected %s header %v, but got %v", name, value, response.Headers[name])
+				}
+			}
+		})
+	}
+}
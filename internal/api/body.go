@@ -0,0 +1,155 @@
+package api
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DefaultMaxBodySize is the default maximum size of request bodies,
+// the payload limit of synchronous Lambda invocations.
+const DefaultMaxBodySize = 6 << 20
+
+var (
+	errTooLarge            = errors.New("request body too large")
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+)
+
+// maxBodySizeFromEnv returns the maximum size of request bodies in bytes
+// of the MAX_BODY_SIZE environment variable, DefaultMaxBodySize by default.
+func maxBodySizeFromEnv() int64 {
+	s := os.Getenv("MAX_BODY_SIZE")
+	if s == "" {
+		return DefaultMaxBodySize
+	}
+	size, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || size <= 0 {
+		log.Printf("invalid max body size %q, using %d", s, DefaultMaxBodySize)
+		return DefaultMaxBodySize
+	}
+	return size
+}
+
+// Body decodes the bodies of requests for the handlers: base64 encoded bodies
+// are decoded and bodies compressed with gzip or deflate are decompressed.
+// Bodies larger than the maximum size, compressed or not, are rejected with
+// 413 Payload Too Large, and bodies that are not JSON or compressed with another
+// coding with 415 Unsupported Media Type. Bodies without a content type are JSON.
+func Body(maxSize int64) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(
+			ctx context.Context,
+			req events.APIGatewayProxyRequest,
+		) (events.APIGatewayProxyResponse, error) {
+			if req.Body == "" {
+				return next(ctx, req)
+			}
+
+			if err := checkContentType(header(req, "Content-Type")); err != nil {
+				return handleErrorCode(http.StatusUnsupportedMediaType, "unsupported request body: %w", err)
+			}
+
+			body := []byte(req.Body)
+			if req.IsBase64Encoded {
+				var err error
+				if body, err = base64.StdEncoding.DecodeString(req.Body); err != nil {
+					return handleErrorCode(http.StatusBadRequest, "failed to decode base64 request body: %w", err)
+				}
+			}
+			if int64(len(body)) > maxSize {
+				return handleErrorCode(http.StatusRequestEntityTooLarge, "request body larger than %d bytes", maxSize)
+			}
+
+			body, err := decompress(body, header(req, "Content-Encoding"), maxSize)
+			switch {
+			case errors.Is(err, errTooLarge):
+				return handleErrorCode(http.StatusRequestEntityTooLarge, "request body larger than %d bytes", maxSize)
+			case errors.Is(err, errUnsupportedEncoding):
+				return handleErrorCode(
+					http.StatusUnsupportedMediaType,
+					"unsupported request body: %w %q",
+					err,
+					header(req, "Content-Encoding"),
+				)
+			case err != nil:
+				return handleErrorCode(http.StatusBadRequest, "failed to decompress request body: %w", err)
+			}
+
+			req.Body = string(body)
+			req.IsBase64Encoded = false
+			return next(ctx, req)
+		}
+	}
+}
+
+// checkContentType checks that a content type is JSON, application/json or
+// a structured syntax suffix such as application/problem+json, in UTF-8.
+func checkContentType(contentType string) error {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+	if mediaType != "application/json" &&
+		!(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")) {
+		return fmt.Errorf("content type %s is not JSON", mediaType)
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return fmt.Errorf("charset %s is not UTF-8", charset)
+	}
+	return nil
+}
+
+// decompress decompresses a body of the content encoding, at most maxSize bytes.
+// The deflate coding is zlib compressed, raw deflate is accepted as some clients send it.
+func decompress(body []byte, encoding string, maxSize int64) ([]byte, error) {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	case "deflate":
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			fr := flate.NewReader(bytes.NewReader(body))
+			defer fr.Close()
+			r = fr
+		} else {
+			defer zr.Close()
+			r = zr
+		}
+	default:
+		return nil, errUnsupportedEncoding
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > maxSize {
+		return nil, errTooLarge
+	}
+	return b, nil
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func compress(t *testing.T, encoding, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	if _, err := io.WriteString(w, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestBody(t *testing.T) {
+	const body = `{"amount": 10}`
+	var got events.APIGatewayProxyRequest
+	h := Body(64)(func(
+		_ context.Context,
+		req events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		got = req
+		return handleOK(nil)
+	})
+
+	tests := []struct {
+		name     string
+		req      events.APIGatewayProxyRequest
+		want     int
+		wantBody string
+	}{
+		{
+			name:     "plain",
+			req:      events.APIGatewayProxyRequest{Body: body},
+			want:     http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name: "base64",
+			req: events.APIGatewayProxyRequest{
+				Headers:         map[string]string{"Content-Type": "application/json; charset=UTF-8"},
+				Body:            base64.StdEncoding.EncodeToString([]byte(body)),
+				IsBase64Encoded: true,
+			},
+			want:     http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name: "gzip",
+			req: events.APIGatewayProxyRequest{
+				Headers:         map[string]string{"content-encoding": "gzip"},
+				Body:            compress(t, "gzip", body),
+				IsBase64Encoded: true,
+			},
+			want:     http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name: "deflate",
+			req: events.APIGatewayProxyRequest{
+				Headers:         map[string]string{"Content-Encoding": "deflate"},
+				Body:            compress(t, "deflate", body),
+				IsBase64Encoded: true,
+			},
+			want:     http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name: "raw deflate",
+			req: events.APIGatewayProxyRequest{
+				Headers:         map[string]string{"Content-Encoding": "deflate"},
+				Body:            compress(t, "raw deflate", body),
+				IsBase64Encoded: true,
+			},
+			want:     http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name: "problem json",
+			req: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Type": "application/merge-patch+json"},
+				Body:    body,
+			},
+			want:     http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name: "invalid base64",
+			req:  events.APIGatewayProxyRequest{Body: "not base64!", IsBase64Encoded: true},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "corrupt gzip",
+			req: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Encoding": "gzip"},
+				Body:    body,
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "too large",
+			req:  events.APIGatewayProxyRequest{Body: strings.Repeat(" ", 65)},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "too large decompressed",
+			req: events.APIGatewayProxyRequest{
+				Headers:         map[string]string{"Content-Encoding": "gzip"},
+				Body:            compress(t, "gzip", strings.Repeat(" ", 1000)),
+				IsBase64Encoded: true,
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "form",
+			req: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+				Body:    body,
+			},
+			want: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "charset",
+			req: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Type": "application/json; charset=latin1"},
+				Body:    body,
+			},
+			want: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "unknown encoding",
+			req: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Encoding": "br"},
+				Body:    body,
+			},
+			want: http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = events.APIGatewayProxyRequest{}
+			res, err := h(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.want {
+				t.Fatalf("unexpected status %d, want %d: %s", res.StatusCode, tt.want, res.Body)
+			}
+			if tt.want == http.StatusOK && (got.Body != tt.wantBody || got.IsBase64Encoded) {
+				t.Errorf("unexpected body %q", got.Body)
+			}
+		})
+	}
+}
+
+func TestBody_Empty(t *testing.T) {
+	h := Body(64)(echo)
+	res, _ := h(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Content-Type": "text/plain"},
+	})
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected requests without body to pass, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// CompressMinSize is the size of the smallest response bodies compressed,
+// smaller bodies do not gain from compression.
+const CompressMinSize = 1024
+
+// Compress compresses the bodies of responses with gzip when the Accept-Encoding
+// header of the request allows it. Compressed bodies are base64 encoded, and the
+// ETag of the response is changed as the compressed representation differs.
+func Compress(minSize int) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(
+			ctx context.Context,
+			req events.APIGatewayProxyRequest,
+		) (events.APIGatewayProxyResponse, error) {
+			res, err := next(ctx, req)
+			if err != nil || res.StatusCode == http.StatusNotModified || res.StatusCode == http.StatusNoContent {
+				return res, err
+			}
+			addVary(&res, "Accept-Encoding")
+
+			if res.IsBase64Encoded || len(res.Body) < minSize || res.Headers["Content-Encoding"] != "" ||
+				!acceptsEncoding(header(req, "Accept-Encoding"), "gzip") {
+				return res, nil
+			}
+
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			if _, err := zw.Write([]byte(res.Body)); err != nil {
+				return handleError("failed to compress response: %w", err)
+			}
+			if err := zw.Close(); err != nil {
+				return handleError("failed to compress response: %w", err)
+			}
+
+			res.Body = base64.StdEncoding.EncodeToString(buf.Bytes())
+			res.IsBase64Encoded = true
+			setHeader(&res, "Content-Encoding", "gzip")
+			if tag := res.Headers["ETag"]; tag != "" {
+				res.Headers["ETag"] = compressedETag(tag)
+			}
+			return res, nil
+		}
+	}
+}
+
+// compressedETag returns the entity tag of the gzip compressed representation
+// of the representation with the entity tag.
+func compressedETag(tag string) string {
+	return strings.TrimSuffix(tag, `"`) + `-gzip"`
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header accepts the content coding,
+// given with a non-zero quality value, or matched by * when it is not given.
+func acceptsEncoding(acceptEncoding, coding string) bool {
+	var accepted, wildcard, found bool
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseQuality(part)
+		switch {
+		case strings.EqualFold(name, coding):
+			found, accepted = true, q > 0
+		case name == "*":
+			wildcard = q > 0
+		}
+	}
+	if found {
+		return accepted
+	}
+	return wildcard
+}
+
+// parseQuality returns the value and the quality value of an element of a header
+// such as Accept or Accept-Encoding, e.g. gzip;q=0.5. The quality is 1 by default.
+func parseQuality(element string) (string, float64) {
+	params := strings.Split(element, ";")
+	q := 1.0
+	for _, p := range params[1:] {
+		p = strings.TrimSpace(p)
+		if len(p) > 2 && strings.EqualFold(p[:2], "q=") {
+			if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return strings.TrimSpace(params[0]), q
+}
+
+// addVary adds a header to the Vary header of the response.
+func addVary(res *events.APIGatewayProxyResponse, name string) {
+	vary := res.Headers["Vary"]
+	for _, v := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), name) {
+			return
+		}
+	}
+	if vary != "" {
+		name = vary + ", " + name
+	}
+	setHeader(res, "Vary", name)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"br", false},
+		{"*", true},
+		{"*, gzip;q=0", false},
+		{"GZIP", true},
+	}
+	for _, tt := range tests {
+		if got := acceptsEncoding(tt.header, "gzip"); got != tt.want {
+			t.Errorf("acceptsEncoding(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"tr_id":"1"}`, 100)
+	h := Compress(CompressMinSize)(ETag(func(
+		context.Context,
+		events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: body}, nil
+	}))
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Headers:    map[string]string{"Accept-Encoding": "gzip, deflate"},
+	}
+
+	res, err := h(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsBase64Encoded || res.Headers["Content-Encoding"] != "gzip" || res.Headers["Vary"] != "Accept-Encoding" {
+		t.Fatalf("unexpected response headers %v", res.Headers)
+	}
+	if res.Headers["ETag"] != compressedETag(etag(body)) {
+		t.Errorf("unexpected ETag %q", res.Headers["ETag"])
+	}
+
+	b, err := base64.StdEncoding.DecodeString(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(zr)
+	if string(got) != body {
+		t.Errorf("unexpected decompressed body %q", got)
+	}
+
+	// the compressed ETag is not modified
+	req.Headers["If-None-Match"] = res.Headers["ETag"]
+	if res, _ := h(context.Background(), req); res.StatusCode != http.StatusNotModified || res.IsBase64Encoded {
+		t.Errorf("unexpected response %d", res.StatusCode)
+	}
+}
+
+func TestCompress_Skip(t *testing.T) {
+	small := Compress(CompressMinSize)(echo)
+	res, _ := small(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Accept-Encoding": "gzip"},
+	})
+	if res.IsBase64Encoded || res.Headers["Content-Encoding"] != "" || res.Headers["Vary"] != "Accept-Encoding" {
+		t.Errorf("expected small bodies not to be compressed, got %v", res.Headers)
+	}
+
+	res, _ = Compress(0)(echo)(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Accept-Encoding": "identity"},
+	})
+	if res.IsBase64Encoded {
+		t.Errorf("expected bodies not to be compressed without gzip accepted")
+	}
+}
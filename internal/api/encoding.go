@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+	"transactions/internal/export"
+	"transactions/internal/msgpack"
+)
+
+var (
+	// ErrUnknownFormat is returned for format query parameters without an encoder.
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrNotAcceptable is returned when no encoder has a media type the Accept header accepts.
+	ErrNotAcceptable = errors.New("no acceptable media type")
+)
+
+// Encoder encodes response bodies in a media type.
+type Encoder struct {
+	// Format is the value of the format query parameter selecting the encoder.
+	Format string
+	// ContentType is the content type of the responses.
+	ContentType string
+	// MediaTypes are the media types of the Accept header selecting the encoder.
+	MediaTypes []string
+	// Binary encoders have their bodies base64 encoded in responses.
+	Binary bool
+	Encode func(v interface{}) ([]byte, error)
+}
+
+// Encoders is a registry of encoders, selected by the format query parameter of
+// a request or negotiated with its Accept header. The first encoder is the default.
+type Encoders struct {
+	encoders []Encoder
+}
+
+// Register registers an encoder.
+func (e *Encoders) Register(enc Encoder) {
+	e.encoders = append(e.encoders, enc)
+}
+
+// Negotiate returns the encoder of the format query parameter of the request,
+// or the encoder with the media type of the highest quality in the Accept header.
+// Ties go to the encoder registered first, as do requests without Accept header.
+func (e *Encoders) Negotiate(req events.APIGatewayProxyRequest) (Encoder, error) {
+	if format := req.QueryStringParameters["format"]; format != "" {
+		for _, enc := range e.encoders {
+			if strings.EqualFold(enc.Format, format) {
+				return enc, nil
+			}
+		}
+		return Encoder{}, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+
+	accept := header(req, "Accept")
+	if strings.TrimSpace(accept) == "" {
+		return e.encoders[0], nil
+	}
+
+	var best Encoder
+	var bestQ float64
+	for _, enc := range e.encoders {
+		if q := acceptQuality(accept, enc.MediaTypes); q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	if bestQ == 0 {
+		return Encoder{}, fmt.Errorf("%w in %s", ErrNotAcceptable, accept)
+	}
+	return best, nil
+}
+
+// acceptQuality returns the quality an Accept header gives to the media types,
+// the quality of the most specific media range matching one of them.
+func acceptQuality(accept string, mediaTypes []string) float64 {
+	var q float64
+	specificity := -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, rangeQ := parseQuality(part)
+		mediaRange = strings.ToLower(mediaRange)
+		for _, mt := range mediaTypes {
+			s := -1
+			switch {
+			case mediaRange == mt:
+				s = 2
+			case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(mediaRange, "*")):
+				s = 1
+			case mediaRange == "*/*":
+				s = 0
+			}
+			if s < 0 {
+				continue
+			}
+			if s > specificity || s == specificity && rangeQ > q {
+				q, specificity = rangeQ, s
+			}
+		}
+	}
+	return q
+}
+
+// encoders are the encoders of the list and get transaction responses.
+var encoders = newEncoders()
+
+func newEncoders() *Encoders {
+	e := &Encoders{}
+	e.Register(Encoder{
+		Format:      "json",
+		ContentType: "application/json",
+		MediaTypes:  []string{"application/json"},
+		Encode:      json.Marshal,
+	})
+	e.Register(Encoder{
+		Format:      "ndjson",
+		ContentType: export.FormatNDJSON.ContentType(),
+		MediaTypes:  []string{"application/x-ndjson", "application/ndjson"},
+		Encode:      encodeNDJSON,
+	})
+	e.Register(Encoder{
+		Format:      "csv",
+		ContentType: export.FormatCSV.ContentType(),
+		MediaTypes:  []string{"text/csv"},
+		Encode:      encodeCSV,
+	})
+	e.Register(Encoder{
+		Format:      "msgpack",
+		ContentType: msgpack.ContentType,
+		MediaTypes:  []string{msgpack.ContentType, "application/x-msgpack", "application/vnd.msgpack"},
+		Binary:      true,
+		Encode:      msgpack.Marshal,
+	})
+	return e
+}
+
+// transactionRows returns the transactions of a list or get response as rows,
+// a transaction being followed by its refunds.
+func transactionRows(v interface{}) ([]db.Transaction, error) {
+	switch v := v.(type) {
+	case db.ListResponse:
+		return v.Items, nil
+	case *db.ListResponse:
+		return v.Items, nil
+	case db.TransactionDetails:
+		return append([]db.Transaction{v.Transaction}, v.Refunds...), nil
+	case db.Transaction:
+		return []db.Transaction{v}, nil
+	case *db.Transaction:
+		return []db.Transaction{*v}, nil
+	case ListResponseV1:
+		return v.Items, nil
+	case ListResponseV2:
+		return v.Data, nil
+	case ItemV2:
+		return transactionRows(v.Data)
+	}
+	return nil, fmt.Errorf("%w: cannot encode %T as rows", ErrNotAcceptable, v)
+}
+
+// encodeNDJSON encodes the transactions of a response as JSON lines.
+func encodeNDJSON(v interface{}) ([]byte, error) {
+	return encodeRows(v, export.FormatNDJSON)
+}
+
+// encodeCSV encodes the transactions of a response as CSV with the export columns.
+func encodeCSV(v interface{}) ([]byte, error) {
+	return encodeRows(v, export.FormatCSV)
+}
+
+func encodeRows(v interface{}, f export.Format) ([]byte, error) {
+	rows, err := transactionRows(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := export.NewWriter(&buf, f, export.DefaultAccounts)
+	if err != nil {
+		return nil, err
+	}
+	for _, tr := range rows {
+		if err := w.Write(tr); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// handleEncoded handles successful requests responding with the body encoded
+// by the encoder negotiated with the request.
+func handleEncoded(req events.APIGatewayProxyRequest, body interface{}) (events.APIGatewayProxyResponse, error) {
+	enc, err := encoders.Negotiate(req)
+	switch {
+	case errors.Is(err, ErrUnknownFormat):
+		return handleErrorCode(http.StatusBadRequest, "failed to encode response: %w", err)
+	case err != nil:
+		return handleErrorCode(http.StatusNotAcceptable, "failed to encode response: %w", err)
+	}
+
+	b, err := enc.Encode(body)
+	if errors.Is(err, ErrNotAcceptable) {
+		return handleErrorCode(http.StatusNotAcceptable, "failed to encode response: %w", err)
+	}
+	if err != nil {
+		return handleError("failed to encode response: %w", err)
+	}
+
+	res := events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": enc.ContentType},
+		Body:       string(b),
+	}
+	if enc.Binary {
+		res.Body = base64.StdEncoding.EncodeToString(b)
+		res.IsBase64Encoded = true
+	}
+	addVary(&res, "Accept")
+	return res, nil
+}
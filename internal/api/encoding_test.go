@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+	"transactions/internal/msgpack"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		format string
+		want   string
+		err    error
+	}{
+		{accept: "", want: "json"},
+		{accept: "*/*", want: "json"},
+		{accept: "text/csv", want: "csv"},
+		{accept: "text/*", want: "csv"},
+		{accept: "application/x-ndjson", want: "ndjson"},
+		{accept: "application/vnd.msgpack", want: "msgpack"},
+		{accept: "application/json;q=0.5, text/csv", want: "csv"},
+		{accept: "text/csv;q=0.2, */*;q=0.5", want: "json"},
+		{accept: "application/*;q=0.1, application/msgpack", want: "msgpack"},
+		{accept: "Text/CSV", want: "csv"},
+		{accept: "text/html", err: ErrNotAcceptable},
+		{accept: "text/csv;q=0", err: ErrNotAcceptable},
+		{accept: "text/html", format: "CSV", want: "csv"},
+		{format: "xml", err: ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		req := events.APIGatewayProxyRequest{
+			Headers:               map[string]string{"Accept": tt.accept},
+			QueryStringParameters: map[string]string{"format": tt.format},
+		}
+		enc, err := encoders.Negotiate(req)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Negotiate(%q, %q) error = %v, want %v", tt.accept, tt.format, err, tt.err)
+			continue
+		}
+		if enc.Format != tt.want {
+			t.Errorf("Negotiate(%q, %q) = %s, want %s", tt.accept, tt.format, enc.Format, tt.want)
+		}
+	}
+}
+
+func TestHandleEncoded(t *testing.T) {
+	list := db.ListResponse{Items: []db.Transaction{
+		{UserID: "john", ID: "a", Timestamp: "2024-01-02T10:00:00.000000Z", Amount: 12.5},
+		{UserID: "john", ID: "b", Timestamp: "2024-01-03T10:00:00.000000Z", Amount: 12.5},
+	}}
+
+	encode := func(accept, format string, body interface{}) events.APIGatewayProxyResponse {
+		t.Helper()
+		res, err := handleEncoded(events.APIGatewayProxyRequest{
+			Headers:               map[string]string{"Accept": accept},
+			QueryStringParameters: map[string]string{"format": format},
+		}, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	b, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := encode("application/json", "", list)
+	if res.StatusCode != http.StatusOK || res.Body != string(b) ||
+		res.Headers["Content-Type"] != "application/json" || res.Headers["Vary"] != "Accept" {
+		t.Errorf("unexpected JSON response %+v", res)
+	}
+
+	res = encode("", "ndjson", list)
+	if lines := strings.Split(strings.TrimSpace(res.Body), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"b"`) {
+		t.Errorf("unexpected NDJSON body %q", res.Body)
+	}
+	if res.Headers["Content-Type"] != "application/x-ndjson" {
+		t.Errorf("unexpected NDJSON content type %q", res.Headers["Content-Type"])
+	}
+
+	res = encode("text/csv", "", list)
+	if lines := strings.Split(strings.TrimSpace(res.Body), "\n"); len(lines) != 3 {
+		t.Errorf("expected a header and 2 rows, got %q", res.Body)
+	}
+	if !strings.HasPrefix(res.Headers["Content-Type"], "text/csv") {
+		t.Errorf("unexpected CSV content type %q", res.Headers["Content-Type"])
+	}
+
+	res = encode("text/csv", "", db.TransactionDetails{Transaction: list.Items[0], Refunds: list.Items[1:]})
+	if lines := strings.Split(strings.TrimSpace(res.Body), "\n"); len(lines) != 3 {
+		t.Errorf("expected the transaction followed by its refund, got %q", res.Body)
+	}
+
+	res = encode("application/msgpack", "", list.Items[0])
+	want, err := msgpack.Marshal(list.Items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsBase64Encoded || res.Body != base64.StdEncoding.EncodeToString(want) {
+		t.Errorf("unexpected MessagePack response %+v", res)
+	}
+
+	if res = encode("text/html", "", list); res.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("expected status %d, got %d", http.StatusNotAcceptable, res.StatusCode)
+	}
+	if res = encode("", "xml", list); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, res.StatusCode)
+	}
+	if res = encode("text/csv", "", map[string]int{"total": 1}); res.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("expected status %d for a body without rows, got %d", http.StatusNotAcceptable, res.StatusCode)
+	}
+}
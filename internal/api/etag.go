@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ETag sets a strong ETag computed from the body of successful GET responses,
+// and responds with 304 Not Modified without body when the If-None-Match header
+// of the request matches it.
+func ETag(next HandlerFunc) HandlerFunc {
+	return func(
+		ctx context.Context,
+		req events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		res, err := next(ctx, req)
+		if err != nil || req.HTTPMethod != http.MethodGet || res.StatusCode != http.StatusOK {
+			return res, err
+		}
+
+		tag := etag(res.Body)
+		setHeader(&res, "ETag", tag)
+		if noneMatch := header(req, "If-None-Match"); noneMatch != "" && etagMatch(noneMatch, tag) {
+			res.StatusCode = http.StatusNotModified
+			res.Body = ""
+			res.IsBase64Encoded = false
+		}
+		return res, nil
+	}
+}
+
+// etag returns the strong entity tag of a body.
+func etag(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatch reports whether the entity tags of an If-None-Match header match
+// the entity tag, with the weak comparison. The tags of compressed representations
+// match the tag of the representation they compress.
+func etagMatch(noneMatch, tag string) bool {
+	for _, t := range strings.Split(noneMatch, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == tag || t == compressedETag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// setHeader sets a header of the response.
+func setHeader(res *events.APIGatewayProxyResponse, name, value string) {
+	if res.Headers == nil {
+		res.Headers = make(map[string]string)
+	}
+	res.Headers[name] = value
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestETag(t *testing.T) {
+	h := ETag(func(
+		context.Context,
+		events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		return handleOK(map[string]string{"ts": "2024"})
+	})
+	get := func(noneMatch string) events.APIGatewayProxyResponse {
+		t.Helper()
+		req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet}
+		if noneMatch != "" {
+			req.Headers = map[string]string{"If-None-Match": noneMatch}
+		}
+		res, err := h(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := get("")
+	tag := res.Headers["ETag"]
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(tag, `"`) || strings.HasPrefix(tag, "W/") {
+		t.Fatalf("unexpected response %d with ETag %q", res.StatusCode, tag)
+	}
+	if again := get(""); again.Headers["ETag"] != tag {
+		t.Errorf("expected the same ETag for the same body, got %q and %q", tag, again.Headers["ETag"])
+	}
+
+	for _, noneMatch := range []string{tag, `"other", ` + tag, "W/" + tag, compressedETag(tag), "*"} {
+		res := get(noneMatch)
+		if res.StatusCode != http.StatusNotModified || res.Body != "" || res.Headers["ETag"] != tag {
+			t.Errorf("If-None-Match %s: unexpected response %d %q", noneMatch, res.StatusCode, res.Body)
+		}
+	}
+	if res := get(`"other"`); res.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status %d for a stale ETag", res.StatusCode)
+	}
+}
+
+func TestETag_NotGet(t *testing.T) {
+	res, _ := ETag(echo)(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost})
+	if _, ok := res.Headers["ETag"]; ok {
+		t.Errorf("unexpected ETag for a POST request")
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+	"transactions/internal/export"
+)
+
+// MaxExportSize is the maximum size of synchronous exports, below the 6 MB
+// payload limit of synchronous Lambda invocations to leave room for the
+// escaping of the body in the response. Larger exports are made by export jobs.
+const MaxExportSize = 5 << 20
+
+// errExportTooLarge is returned when a synchronous export exceeds MaxExportSize.
+var errExportTooLarge = errors.New("export too large")
+
+// exportBuffer is a buffer failing writes beyond MaxExportSize with errExportTooLarge.
+type exportBuffer struct {
+	bytes.Buffer
+}
+
+func (b *exportBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > MaxExportSize {
+		return 0, errExportTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// handleExport handles GET /users/{user_id}/export?format=csv|ndjson|ledger|beancount|iif&from=&to= requests.
+// It writes every matching transaction, following the query cursors to the last page.
+// It accepts the filter query parameters of the list request; without from and to
+// all the transactions of the user are exported. Exports larger than MaxExportSize
+// are responded with 413 Payload Too Large, they are made with POST /exports.
+func handleExport(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	format, err := export.ParseFormat(req.QueryStringParameters["format"])
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to parse format query param: %w", err)
+	}
+
+	listReq, err := db.UserListRequestFromAPIGatewayProxyRequest(req)
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to parse request: %w", err)
+	}
+	if err := listReq.ValidateExport(); err != nil {
+		return handleError("invalid export request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	accounts, err := export.AccountsFromEnv()
+	if err != nil {
+		return handleError("failed to get account mapping: %w", err)
+	}
+
+	var buf exportBuffer
+	w, err := export.NewWriter(&buf, format, accounts)
+	if err != nil {
+		return handleError("failed to make export writer: %w", err)
+	}
+	err = client.ForEach(ctx, listReq, w.Write)
+	if err == nil {
+		err = w.Flush()
+	}
+	if errors.Is(err, errExportTooLarge) {
+		return handleErrorCode(
+			http.StatusRequestEntityTooLarge,
+			"export larger than %d bytes, create an export job with POST /exports instead",
+			MaxExportSize,
+		)
+	}
+	if err != nil {
+		return handleError("failed to export records: %w", err)
+	}
+
+	filename := export.Filename(listReq.UserID, listReq.From, listReq.To, format)
+	return events.APIGatewayProxyResponse{
+		Body:       buf.String(),
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":        format.ContentType(),
+			"Content-Disposition": export.ContentDisposition(filename),
+		},
+	}, nil
+}
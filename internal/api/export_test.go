@@ -0,0 +1,30 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"transactions/internal/db"
+	"transactions/internal/export"
+)
+
+func TestExportBuffer(t *testing.T) {
+	var buf exportBuffer
+	w := export.NewCSVWriter(&buf)
+	tr := db.Transaction{UserID: "john", Description: strings.Repeat("a", 500)}
+
+	var err error
+	for i := 0; err == nil && i < MaxExportSize/500; i++ {
+		err = w.Write(tr)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if !errors.Is(err, errExportTooLarge) {
+		t.Errorf("expected error %v, got %v", errExportTooLarge, err)
+	}
+	if buf.Len() > MaxExportSize {
+		t.Errorf("expected at most %d bytes, got %d", MaxExportSize, buf.Len())
+	}
+}
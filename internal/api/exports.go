@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+	"transactions/internal/export"
+	"transactions/internal/store"
+)
+
+var exportStore = store.FromEnv()
+
+// exportResponse represents an export job with the download location of its file.
+type exportResponse struct {
+	db.ExportJob
+	Location string `json:"location,omitempty"`
+}
+
+// handleCreateExport handles POST /exports requests.
+// The job is processed asynchronously by the export worker.
+func handleCreateExport(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	var job db.ExportJob
+
+	dec := json.NewDecoder(strings.NewReader(req.Body))
+	if err := dec.Decode(&job); err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+	}
+
+	format, err := export.ParseFormat(job.Format)
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to parse format: %w", err)
+	}
+	job = db.ExportJob{
+		UserID: job.UserID,
+		Format: string(format),
+		From:   job.From,
+		To:     job.To,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, INSERT_TIMEOUT)
+	defer cancel()
+	if err := client.CreateExportJob(ctx, &job); err != nil {
+		return handleError("failed to create export: %w", err)
+	}
+
+	return handleJSON(http.StatusAccepted, mapper(req).Item(exportResponse{ExportJob: job}))
+}
+
+// handleGetExport handles GET /exports/{export_id} requests.
+// Completed jobs include the download location of the export file.
+func handleGetExport(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	job, err := client.GetExportJob(ctx, req.PathParameters["export_id"])
+	if err != nil {
+		return handleError("failed to get export: %w", err)
+	}
+
+	resp := exportResponse{ExportJob: job}
+	if job.Status == db.ExportCompleted {
+		if resp.Location, err = exportStore.Location(ctx, job.File); err != nil {
+			return handleError("failed to get export location: %w", err)
+		}
+	}
+	return handleOK(mapper(req).Item(resp))
+}
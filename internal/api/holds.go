@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+)
+
+const HOLD_TIMEOUT = 10 * time.Second
+
+// handleCreateHold handles POST /users/{user_id}/holds requests, responding
+// with 201 Created and the URL of the hold in the Location header.
+func handleCreateHold(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	var h db.Hold
+
+	dec := json.NewDecoder(strings.NewReader(req.Body))
+	if err := dec.Decode(&h); err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+	}
+	h.UserID = req.PathParameters["user_id"]
+
+	ctx, cancel := context.WithTimeout(ctx, HOLD_TIMEOUT)
+	defer cancel()
+	if err := client.CreateHold(ctx, &h); err != nil {
+		return handleError("failed to create hold: %w", err)
+	}
+
+	res, err := handleJSON(http.StatusCreated, mapper(req).Item(h))
+	if res.StatusCode == http.StatusCreated {
+		setHeader(&res, "Location", holdURL(req, h))
+	}
+	return res, err
+}
+
+// handleGetHold handles GET /users/{user_id}/holds/{hold_id} requests.
+func handleGetHold(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, HOLD_TIMEOUT)
+	defer cancel()
+
+	h, err := client.GetHold(ctx, req.PathParameters["user_id"], req.PathParameters["hold_id"])
+	if err != nil {
+		return handleError("failed to get hold: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(h))
+}
+
+// handleListHolds handles GET /users/{user_id}/holds requests.
+func handleListHolds(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, HOLD_TIMEOUT)
+	defer cancel()
+
+	holds, err := client.ListHolds(ctx, req.PathParameters["user_id"])
+	if err != nil {
+		return handleError("failed to list holds: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(holds))
+}
+
+// handleCaptureHold handles POST /users/{user_id}/holds/{hold_id}/capture requests.
+// The optional body {"amount": 10} captures part of the hold, otherwise the full hold is captured.
+func handleCaptureHold(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	var body struct {
+		Amount float64 `json:"amount"`
+	}
+	if strings.TrimSpace(req.Body) != "" {
+		dec := json.NewDecoder(strings.NewReader(req.Body))
+		if err := dec.Decode(&body); err != nil {
+			return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, HOLD_TIMEOUT)
+	defer cancel()
+
+	tr, err := client.CaptureHold(
+		ctx,
+		req.PathParameters["user_id"],
+		req.PathParameters["hold_id"],
+		body.Amount,
+	)
+	if err != nil {
+		return handleError("failed to capture hold: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(tr))
+}
+
+// handleReleaseHold handles POST /users/{user_id}/holds/{hold_id}/release requests.
+func handleReleaseHold(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, HOLD_TIMEOUT)
+	defer cancel()
+
+	h, err := client.ReleaseHold(ctx, req.PathParameters["user_id"], req.PathParameters["hold_id"])
+	if err != nil {
+		return handleError("failed to release hold: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(h))
+}
+
+// handleBalance handles GET /users/{user_id}/balance requests.
+func handleBalance(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	b, err := client.Balance(ctx, req.PathParameters["user_id"])
+	if err != nil {
+		return handleError("failed to compute balance: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(b))
+}
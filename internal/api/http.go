@@ -0,0 +1,141 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// localStage is the stage of the requests served over net/http.
+const localStage = "local"
+
+// HTTPHandler serves a handler of API Gateway proxy requests over net/http,
+// translating the requests into proxy events the way API Gateway does.
+type HTTPHandler struct {
+	Handler HandlerFunc
+}
+
+// ServeHTTP serves an HTTP request. Errors returned by the handler are responded
+// with 502 Bad Gateway, as API Gateway does.
+func (h HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := NewProxyRequest(r)
+	if err != nil {
+		log.Printf("ERROR: failed to read request body: %s", err)
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.Handler(r.Context(), req)
+	if err != nil {
+		log.Printf("ERROR: %s", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, `{"message": "Internal server error"}`)
+		return
+	}
+
+	if err := WriteProxyResponse(w, res); err != nil {
+		log.Printf("ERROR: failed to write response: %s", err)
+	}
+}
+
+// NewProxyRequest translates an HTTP request into an API Gateway proxy request
+// without a resource, to be routed on its path. Bodies that are not valid UTF-8
+// are base64 encoded.
+func NewProxyRequest(r *http.Request) (events.APIGatewayProxyRequest, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return events.APIGatewayProxyRequest{}, err
+		}
+	}
+
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod:                      r.Method,
+		Path:                            r.URL.EscapedPath(),
+		Headers:                         make(map[string]string, len(r.Header)+1),
+		MultiValueHeaders:               make(map[string][]string, len(r.Header)+1),
+		QueryStringParameters:           make(map[string]string),
+		MultiValueQueryStringParameters: r.URL.Query(),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID:  requestID(),
+			Stage:      localStage,
+			HTTPMethod: r.Method,
+			Path:       r.URL.EscapedPath(),
+			Identity:   events.APIGatewayRequestIdentity{SourceIP: sourceIP(r), UserAgent: r.UserAgent()},
+		},
+	}
+	for k, v := range r.Header {
+		req.Headers[k] = v[0]
+		req.MultiValueHeaders[k] = v
+	}
+	if r.Host != "" {
+		req.Headers["Host"] = r.Host
+		req.MultiValueHeaders["Host"] = []string{r.Host}
+	}
+	for k, v := range req.MultiValueQueryStringParameters {
+		req.QueryStringParameters[k] = v[0]
+	}
+
+	if utf8.Valid(body) {
+		req.Body = string(body)
+	} else {
+		req.Body = base64.StdEncoding.EncodeToString(body)
+		req.IsBase64Encoded = true
+	}
+	return req, nil
+}
+
+// WriteProxyResponse writes an API Gateway proxy response as an HTTP response.
+func WriteProxyResponse(w http.ResponseWriter, res events.APIGatewayProxyResponse) error {
+	body := []byte(res.Body)
+	if res.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(res.Body); err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return err
+		}
+	}
+
+	for k, v := range res.Headers {
+		w.Header().Set(k, v)
+	}
+	for k, vs := range res.MultiValueHeaders {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	status := res.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	return err
+}
+
+// requestID returns a random request ID.
+func requestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// sourceIP returns the IP address of the client of the request.
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	var got events.APIGatewayProxyRequest
+	r := NewRouter()
+	r.Handle(http.MethodPost, "/users/{user_id}/transactions/{ts}/status", func(
+		_ context.Context,
+		req events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		got = req
+		return events.APIGatewayProxyResponse{
+			StatusCode:      http.StatusAccepted,
+			Headers:         map[string]string{"Content-Type": "text/plain"},
+			Body:            base64.StdEncoding.EncodeToString([]byte("done")),
+			IsBase64Encoded: true,
+		}, nil
+	})
+
+	srv := httptest.NewServer(HTTPHandler{Handler: r.Serve})
+	defer srv.Close()
+
+	res, err := http.Post(
+		srv.URL+"/users/john/transactions/2024-01-02T10%3A00%3A00Z/status?force=1&force=2",
+		"application/json",
+		strings.NewReader(`{"status":"voided"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+
+	if res.StatusCode != http.StatusAccepted || string(body) != "done" || res.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("unexpected response %d %v %q", res.StatusCode, res.Header, body)
+	}
+	if got.Resource != "/users/{user_id}/transactions/{ts}/status" ||
+		got.PathParameters["ts"] != "2024-01-02T10:00:00Z" ||
+		got.QueryStringParameters["force"] != "1" ||
+		len(got.MultiValueQueryStringParameters["force"]) != 2 ||
+		got.Headers["Content-Type"] != "application/json" ||
+		got.Body != `{"status":"voided"}` || got.IsBase64Encoded ||
+		got.RequestContext.RequestID == "" || got.RequestContext.Identity.SourceIP != "127.0.0.1" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
+
+func TestHTTPHandler_Error(t *testing.T) {
+	h := HTTPHandler{Handler: func(
+		context.Context,
+		events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{}, errors.New("boom")
+	}}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+}
+
+func TestNewProxyRequest_Binary(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader("\xff\xfe"))
+	req, err := NewProxyRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !req.IsBase64Encoded || req.Body != base64.StdEncoding.EncodeToString([]byte("\xff\xfe")) {
+		t.Errorf("expected a base64 body, got %q", req.Body)
+	}
+}
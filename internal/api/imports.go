@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/importer"
+)
+
+// handleImport handles POST /users/{user_id}/imports requests.
+// The statement is imported as transactions of the user, the response
+// reports the rows that failed and the balances of the statements.
+func handleImport(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	var importReq importer.Request
+
+	dec := json.NewDecoder(strings.NewReader(req.Body))
+	if err := dec.Decode(&importReq); err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+	}
+
+	parser, err := importReq.Parser()
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to make parser: %w", err)
+	}
+	entries, balances, err := importer.Parse(
+		parser,
+		strings.NewReader(importReq.Data),
+		req.PathParameters["user_id"],
+	)
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to parse statement: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, INSERT_TIMEOUT)
+	defer cancel()
+
+	im := importer.Importer{Writer: client, DryRun: importReq.DryRun}
+	report, err := im.Import(ctx, entries)
+	if err != nil {
+		return handleError("failed to import statement: %w", err)
+	}
+	report.Balances = balances
+
+	return handleOK(mapper(req).Item(report))
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+)
+
+// Links are the links of a page of a list.
+type Links struct {
+	Self string `json:"self"`
+	// Next is the link of the next page, empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// stagePath returns the stage of the request when it is in the path of its URL, e.g. /Prod.
+func stagePath(req events.APIGatewayProxyRequest) string {
+	if p := req.RequestContext.Path; req.Path != "" && strings.HasSuffix(p, req.Path) {
+		return strings.TrimSuffix(p, req.Path)
+	}
+	return ""
+}
+
+// basePath returns the path prefix of the URLs of the API for the request:
+// its stage and its version prefix.
+func basePath(req events.APIGatewayProxyRequest) string {
+	base := stagePath(req)
+	if v, _, ok := versionPrefix(req.Resource); ok {
+		base += "/" + v
+	}
+	return base
+}
+
+// transactionURL returns the URL of the transaction, its get route.
+func transactionURL(req events.APIGatewayProxyRequest, pk db.TransactionPK) string {
+	return basePath(req) + "/users/" + url.PathEscape(pk.UserID) + "/transactions/" + url.PathEscape(pk.Timestamp)
+}
+
+// holdURL returns the URL of the hold, its get route.
+func holdURL(req events.APIGatewayProxyRequest, h db.Hold) string {
+	return basePath(req) + "/users/" + url.PathEscape(h.UserID) + "/holds/" + url.PathEscape(h.ID)
+}
+
+// pageLinks returns the links of the page of the request and of the page
+// following it, the request with the cursor in the cursor query parameter.
+func pageLinks(req events.APIGatewayProxyRequest, cursor, cursorParam string) Links {
+	query := url.Values{}
+	for k, v := range req.QueryStringParameters {
+		query.Set(k, v)
+	}
+	for k, v := range req.MultiValueQueryStringParameters {
+		query[k] = v
+	}
+
+	path := req.Path
+	if path == "" {
+		path = req.Resource
+		for k, v := range req.PathParameters {
+			path = strings.Replace(path, "{"+k+"}", url.PathEscape(v), 1)
+		}
+	}
+	path = stagePath(req) + path
+
+	links := Links{Self: withQuery(path, query)}
+	if cursor != "" {
+		query.Set(cursorParam, cursor)
+		links.Next = withQuery(path, query)
+	}
+	return links
+}
+
+// withQuery returns the path with the query, its parameters sorted by key.
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// linkHeader returns the RFC 8288 Link header of the links.
+func linkHeader(links Links) string {
+	header := `<` + links.Self + `>; rel="self"`
+	if links.Next != "" {
+		header += `, <` + links.Next + `>; rel="next"`
+	}
+	return header
+}
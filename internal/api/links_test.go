@@ -0,0 +1,83 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+)
+
+func TestTransactionURL(t *testing.T) {
+	pk := db.TransactionPK{UserID: "john doe", Timestamp: "2024-01-02T10:00:00.000000Z"}
+	tests := []struct {
+		name string
+		req  events.APIGatewayProxyRequest
+		want string
+	}{
+		{
+			name: "unversioned",
+			req:  events.APIGatewayProxyRequest{Resource: "/transactions", Path: "/transactions"},
+			want: "/users/john%20doe/transactions/2024-01-02T10:00:00.000000Z",
+		},
+		{
+			name: "versioned with stage",
+			req: events.APIGatewayProxyRequest{
+				Resource:       "/v2/transactions",
+				Path:           "/v2/transactions",
+				RequestContext: events.APIGatewayProxyRequestContext{Path: "/Prod/v2/transactions"},
+			},
+			want: "/Prod/v2/users/john%20doe/transactions/2024-01-02T10:00:00.000000Z",
+		},
+	}
+	for _, tt := range tests {
+		if got := transactionURL(tt.req, pk); got != tt.want {
+			t.Errorf("%s: transactionURL() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHoldURL(t *testing.T) {
+	h := db.Hold{UserID: "john doe", ID: "h1"}
+	req := events.APIGatewayProxyRequest{
+		Resource:       "/v2/users/{user_id}/holds",
+		Path:           "/v2/users/john doe/holds",
+		RequestContext: events.APIGatewayProxyRequestContext{Path: "/Prod/v2/users/john doe/holds"},
+	}
+	if got, want := holdURL(req, h), "/Prod/v2/users/john%20doe/holds/h1"; got != want {
+		t.Errorf("holdURL() = %s, want %s", got, want)
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Resource:              "/transactions/{user_id}/{ts}",
+		Path:                  "/transactions/john/2024",
+		QueryStringParameters: map[string]string{"limit": "10", "after": "old"},
+		RequestContext:        events.APIGatewayProxyRequestContext{Path: "/Prod/transactions/john/2024"},
+	}
+
+	links := pageLinks(req, "next", "after")
+	if want := "/Prod/transactions/john/2024?after=old&limit=10"; links.Self != want {
+		t.Errorf("expected self link %s, got %s", want, links.Self)
+	}
+	if want := "/Prod/transactions/john/2024?after=next&limit=10"; links.Next != want {
+		t.Errorf("expected next link %s, got %s", want, links.Next)
+	}
+	if want := `</Prod/transactions/john/2024?after=old&limit=10>; rel="self", ` +
+		`</Prod/transactions/john/2024?after=next&limit=10>; rel="next"`; linkHeader(links) != want {
+		t.Errorf("unexpected Link header %s", linkHeader(links))
+	}
+
+	// the last page has no next link, and requests without path are linked by their resource
+	links = pageLinks(events.APIGatewayProxyRequest{
+		Resource:       "/v2/transactions/{user_id}/{ts}",
+		PathParameters: map[string]string{"user_id": "john", "ts": "2024"},
+	}, "", "cursor")
+	if links.Self != "/v2/transactions/john/2024" || links.Next != "" {
+		t.Errorf("unexpected links %+v", links)
+	}
+	if want := `</v2/transactions/john/2024>; rel="self"`; linkHeader(links) != want {
+		t.Errorf("unexpected Link header %s", linkHeader(links))
+	}
+}
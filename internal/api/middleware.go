@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	// REQUEST_TIMEOUT is the longest a request is handled, the integration timeout of API Gateway.
+	REQUEST_TIMEOUT = 29 * time.Second
+	// TIMEOUT_MARGIN is the time left before the deadline of the invocation
+	// to respond to requests timing out.
+	TIMEOUT_MARGIN = 200 * time.Millisecond
+)
+
+// MetricsNamespace is the CloudWatch namespace of the request metrics.
+const MetricsNamespace = "Transactions"
+
+// header returns the value of the request header, matched case insensitively.
+func header(req events.APIGatewayProxyRequest, name string) string {
+	for k, v := range req.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	for k, v := range req.MultiValueHeaders {
+		if strings.EqualFold(k, name) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// routeName names the route of the request in logs and metrics.
+func routeName(req events.APIGatewayProxyRequest) string {
+	return req.HTTPMethod + " " + req.Resource
+}
+
+// Logging logs the route, status code and duration of requests.
+func Logging(next HandlerFunc) HandlerFunc {
+	return func(
+		ctx context.Context,
+		req events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		start := time.Now()
+		res, err := next(ctx, req)
+		log.Printf(
+			"%s %s %s (%s) %d in %s",
+			req.RequestContext.RequestID,
+			req.HTTPMethod,
+			req.Path,
+			req.Resource,
+			res.StatusCode,
+			time.Since(start),
+		)
+		if err != nil {
+			log.Printf("ERROR: %s", err.Error())
+		}
+		return res, err
+	}
+}
+
+// Recover responds to requests panicking with 500 Internal Server Error.
+func Recover(next HandlerFunc) HandlerFunc {
+	return func(
+		ctx context.Context,
+		req events.APIGatewayProxyRequest,
+	) (res events.APIGatewayProxyResponse, err error) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			var stack []byte
+			if pe, ok := p.(*panicError); ok {
+				p, stack = pe.value, pe.stack
+			} else {
+				stack = debug.Stack()
+			}
+			log.Printf("PANIC: %v\n%s", p, stack)
+			res, err = handleErrorCode(http.StatusInternalServerError, "panic: %v", p)
+		}()
+		return next(ctx, req)
+	}
+}
+
+// panicError carries a panic and its stack from the goroutine handling
+// a request with a timeout to the goroutine of the request.
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+// Timeout handles requests with a deadline, the earliest of the timeout and
+// the deadline of the invocation less the margin. Requests still handled at the
+// deadline are responded with 504 Gateway Timeout.
+func Timeout(timeout, margin time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(
+			ctx context.Context,
+			req events.APIGatewayProxyRequest,
+		) (events.APIGatewayProxyResponse, error) {
+			deadline := time.Now().Add(timeout)
+			if d, ok := ctx.Deadline(); ok && d.Add(-margin).Before(deadline) {
+				deadline = d.Add(-margin)
+			}
+			ctx, cancel := context.WithDeadline(ctx, deadline)
+			defer cancel()
+
+			type result struct {
+				res events.APIGatewayProxyResponse
+				err error
+				p   *panicError
+			}
+			done := make(chan result, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						done <- result{p: &panicError{value: p, stack: debug.Stack()}}
+					}
+				}()
+				res, err := next(ctx, req)
+				done <- result{res: res, err: err}
+			}()
+
+			select {
+			case r := <-done:
+				if r.p != nil {
+					panic(r.p)
+				}
+				return r.res, r.err
+			case <-ctx.Done():
+				return handleErrorCode(http.StatusGatewayTimeout, "request timed out: %w", ctx.Err())
+			}
+		}
+	}
+}
+
+// APIKeyAuth rejects requests without one of the keys in the X-Api-Key header
+// with 401 Unauthorized. Requests are not authenticated without keys.
+func APIKeyAuth(keys []string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		if len(keys) == 0 {
+			return next
+		}
+		return func(
+			ctx context.Context,
+			req events.APIGatewayProxyRequest,
+		) (events.APIGatewayProxyResponse, error) {
+			given := []byte(header(req, "X-Api-Key"))
+			for _, key := range keys {
+				if subtle.ConstantTimeCompare(given, []byte(key)) == 1 {
+					return next(ctx, req)
+				}
+			}
+			return handleErrorCode(http.StatusUnauthorized, "missing or invalid API key")
+		}
+	}
+}
+
+// apiKeysFromEnv returns the comma separated API keys of the API_KEYS environment variable.
+func apiKeysFromEnv() []string {
+	var keys []string
+	for _, key := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Metrics writes the latency, client errors and server errors of requests by route
+// in the CloudWatch embedded metric format, extracted from the logs of the function.
+func Metrics(w io.Writer) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(
+			ctx context.Context,
+			req events.APIGatewayProxyRequest,
+		) (events.APIGatewayProxyResponse, error) {
+			start := time.Now()
+			res, err := next(ctx, req)
+			status := res.StatusCode
+			if err != nil {
+				status = http.StatusBadGateway
+			}
+			if merr := writeMetrics(w, start, routeName(req), status); merr != nil {
+				log.Printf("failed to write metrics: %s", merr)
+			}
+			return res, err
+		}
+	}
+}
+
+// writeMetrics writes the metrics of a request as an embedded metric format log line.
+func writeMetrics(w io.Writer, start time.Time, route string, status int) error {
+	var clientErrors, serverErrors int
+	switch {
+	case status >= http.StatusInternalServerError:
+		serverErrors = 1
+	case status >= http.StatusBadRequest:
+		clientErrors = 1
+	}
+
+	type metric struct {
+		Name string
+		Unit string
+	}
+	line := map[string]interface{}{
+		"_aws": map[string]interface{}{
+			"Timestamp": start.UnixNano() / int64(time.Millisecond),
+			"CloudWatchMetrics": []interface{}{map[string]interface{}{
+				"Namespace":  MetricsNamespace,
+				"Dimensions": [][]string{{"Route"}},
+				"Metrics": []metric{
+					{"Latency", "Milliseconds"},
+					{"ClientErrors", "Count"},
+					{"ServerErrors", "Count"},
+				},
+			}},
+		},
+		"Route":        route,
+		"StatusCode":   status,
+		"Latency":      float64(time.Since(start)) / float64(time.Millisecond),
+		"ClientErrors": clientErrors,
+		"ServerErrors": serverErrors,
+	}
+
+	b, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestRecover(t *testing.T) {
+	h := Recover(func(
+		context.Context,
+		events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		panic("boom")
+	})
+
+	res, err := h(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("unexpected status %d", res.StatusCode)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	slow := func(
+		ctx context.Context,
+		_ events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return handleOK("late")
+	}
+
+	res, _ := Timeout(10*time.Millisecond, 0)(slow)(context.Background(), events.APIGatewayProxyRequest{})
+	if res.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	// the deadline of the invocation less the margin comes first
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res, _ = Timeout(time.Minute, 90*time.Millisecond)(slow)(ctx, events.APIGatewayProxyRequest{})
+	if res.StatusCode != http.StatusGatewayTimeout || time.Since(start) > 80*time.Millisecond {
+		t.Errorf("unexpected status %d after %s", res.StatusCode, time.Since(start))
+	}
+}
+
+func TestTimeout_Panic(t *testing.T) {
+	h := Recover(Timeout(time.Second, 0)(func(
+		context.Context,
+		events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		panic("boom")
+	}))
+
+	res, _ := h(context.Background(), events.APIGatewayProxyRequest{})
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("unexpected status %d", res.StatusCode)
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	h := APIKeyAuth([]string{"secret", "other"})(echo)
+
+	tests := []struct {
+		headers map[string]string
+		want    int
+	}{
+		{nil, http.StatusUnauthorized},
+		{map[string]string{"X-Api-Key": "wrong"}, http.StatusUnauthorized},
+		{map[string]string{"X-Api-Key": "secret"}, http.StatusOK},
+		{map[string]string{"x-api-key": "other"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		res, _ := h(context.Background(), events.APIGatewayProxyRequest{Headers: tt.headers})
+		if res.StatusCode != tt.want {
+			t.Errorf("unexpected status %d with headers %v, want %d", res.StatusCode, tt.headers, tt.want)
+		}
+	}
+
+	open := APIKeyAuth(nil)(echo)
+	if res, _ := open(context.Background(), events.APIGatewayProxyRequest{}); res.StatusCode != http.StatusOK {
+		t.Errorf("expected requests to pass without keys, got %d", res.StatusCode)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	h := Metrics(&buf)(notFound)
+
+	if _, err := h(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Resource:   "/users/{user_id}/balance",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var line struct {
+		AWS struct {
+			CloudWatchMetrics []struct {
+				Namespace string
+			}
+		} `json:"_aws"`
+		Route        string
+		StatusCode   int
+		ClientErrors int
+		ServerErrors int
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid metrics line %q: %v", buf.String(), err)
+	}
+	if line.Route != "GET /users/{user_id}/balance" || line.StatusCode != http.StatusNotFound ||
+		line.ClientErrors != 1 || line.ServerErrors != 0 ||
+		len(line.AWS.CloudWatchMetrics) != 1 || line.AWS.CloudWatchMetrics[0].Namespace != MetricsNamespace {
+		t.Errorf("unexpected metrics line %q", buf.String())
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+)
+
+// ProblemContentType is the media type of error responses.
+const ProblemContentType = "application/problem+json"
+
+// retryAfter is the number of seconds clients are asked to wait before
+// retrying throttled requests and requests failing on unavailable services.
+const retryAfter = "1"
+
+// Problem is the RFC 7807 problem details body of error responses.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Detail explains the problem of client errors, it is left
+	// out of server errors to not leak internal details.
+	Detail string `json:"detail,omitempty"`
+	// Errors are the fields failing validation.
+	Errors []db.FieldError `json:"errors,omitempty"`
+}
+
+// errorStatus returns the status code of an error by its kind:
+// 422 for validation errors, 404 for records not found, 409 for
+// conflicts, 429 for throttling, 503 when DynamoDB is unavailable
+// and 500 otherwise.
+func errorStatus(err error) int {
+	var (
+		validation  *db.ValidationError
+		notFound    *db.NotFoundError
+		conflict    *db.ConflictError
+		throttled   *db.ThrottledError
+		unavailable *db.UnavailableError
+	)
+	switch {
+	case errors.As(err, &validation):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.As(err, &conflict):
+		return http.StatusConflict
+	case errors.As(err, &throttled):
+		return http.StatusTooManyRequests
+	case errors.As(err, &unavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// newProblem returns the problem details of an error responded with the status code.
+func newProblem(code int, err error) Problem {
+	p := Problem{Type: "about:blank", Title: http.StatusText(code), Status: code}
+	if code < http.StatusInternalServerError {
+		p.Detail = err.Error()
+	}
+	var validation *db.ValidationError
+	if errors.As(err, &validation) {
+		p.Errors = validation.Fields
+	}
+	return p
+}
+
+// handleProblem logs the error and responds with its problem details.
+func handleProblem(code int, err error) (events.APIGatewayProxyResponse, error) {
+	log.Printf("ERROR: %s", err.Error())
+
+	body, merr := json.Marshal(newProblem(code, err))
+	if merr != nil {
+		return events.APIGatewayProxyResponse{}, merr
+	}
+	headers := map[string]string{"Content-Type": ProblemContentType}
+	if code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable {
+		headers["Retry-After"] = retryAfter
+	}
+	return events.APIGatewayProxyResponse{
+		Body:       string(body),
+		StatusCode: code,
+		Headers:    headers,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/importer"
+	"transactions/internal/reconcile"
+)
+
+// handleReconcile handles POST /users/{user_id}/reconciliations requests.
+// The statement is matched with the transactions of the user stored in the
+// range, the response reports the matched, missing and unexpected items.
+func handleReconcile(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	var reconcileReq reconcile.Request
+
+	dec := json.NewDecoder(strings.NewReader(req.Body))
+	if err := dec.Decode(&reconcileReq); err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+	}
+
+	parser, err := reconcileReq.Parser()
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to make parser: %w", err)
+	}
+	entries, balances, err := importer.Parse(
+		parser,
+		strings.NewReader(reconcileReq.Data),
+		req.PathParameters["user_id"],
+	)
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to parse statement: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	r := reconcile.Reconciler{Lister: client, Options: reconcileReq.Options}
+	report, err := r.Reconcile(ctx, req.PathParameters["user_id"], reconcileReq.From, reconcileReq.To, entries)
+	if errors.Is(err, reconcile.ErrInvalidRange) || errors.Is(err, reconcile.ErrEmptyStatement) {
+		return handleErrorCode(http.StatusBadRequest, "failed to reconcile statement: %w", err)
+	}
+	if err != nil {
+		return handleError("failed to reconcile statement: %w", err)
+	}
+	report.Balances = balances
+
+	return handleOK(mapper(req).Item(report))
+}
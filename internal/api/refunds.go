@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// handleRefund handles POST /users/{user_id}/transactions/{ts}/refunds requests.
+// The body {"amount": 10, "origin": "web"} refunds amount of the transaction,
+// origin defaults to the origin of the original transaction. The refund is
+// responded with 201 Created and its URL in the Location header.
+func handleRefund(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	var body struct {
+		Amount float64 `json:"amount"`
+		Origin string  `json:"origin"`
+	}
+
+	dec := json.NewDecoder(strings.NewReader(req.Body))
+	if err := dec.Decode(&body); err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, INSERT_TIMEOUT)
+	defer cancel()
+
+	refund, err := client.Refund(ctx, transactionPK(req), body.Amount, body.Origin)
+	if err != nil {
+		return handleError("failed to refund: %w", err)
+	}
+
+	res, err := handleJSON(http.StatusCreated, mapper(req).Item(refund))
+	if res.StatusCode == http.StatusCreated {
+		setHeader(&res, "Location", transactionURL(req, refund.PK()))
+	}
+	return res, err
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HandlerFunc handles API Gateway proxy requests.
+type HandlerFunc func(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error)
+
+// Middleware wraps a handler with behaviour shared by all routes.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Router dispatches requests to the handler of their route, the resource path
+// template and the method of the request. Requests are matched on the resource
+// field set by API Gateway, or on their path when the resource is unknown, e.g.
+// with a {proxy+} resource, setting the resource and the path parameters.
+type Router struct {
+	routes     map[string]map[string]HandlerFunc // resource -> method -> handler
+	resources  []resource
+	middleware []Middleware
+}
+
+// resource is a resource path template split in segments.
+type resource struct {
+	template string
+	segments []string
+	literals int
+}
+
+// NewRouter returns a router applying the middleware to every request,
+// the first middleware being the outermost.
+func NewRouter(middleware ...Middleware) *Router {
+	return &Router{
+		routes:     make(map[string]map[string]HandlerFunc),
+		middleware: middleware,
+	}
+}
+
+// Handle registers the handler of the method on the resource path template,
+// e.g. /users/{user_id}/transactions/{ts}.
+func (r *Router) Handle(method, template string, h HandlerFunc) {
+	methods, ok := r.routes[template]
+	if !ok {
+		methods = make(map[string]HandlerFunc)
+		r.routes[template] = methods
+
+		res := resource{template: template, segments: splitPath(template)}
+		for _, s := range res.segments {
+			if !isParam(s) {
+				res.literals++
+			}
+		}
+		r.resources = append(r.resources, res)
+		// the most specific resources are matched first, e.g. /rules/apply before /rules/{rule_id}
+		sort.SliceStable(r.resources, func(i, j int) bool {
+			return r.resources[i].literals > r.resources[j].literals
+		})
+	}
+	methods[method] = h
+}
+
+// Serve routes the request through the middleware to the handler of its route.
+// Requests without a route are responded with 404 Not Found, and requests with
+// a method the resource does not allow with 405 Method Not Allowed.
+func (r *Router) Serve(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	h := r.route(&req)
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	return h(ctx, req)
+}
+
+// route returns the handler of the request, setting its resource and path parameters
+// when it is matched on its path.
+func (r *Router) route(req *events.APIGatewayProxyRequest) HandlerFunc {
+	methods, ok := r.routes[req.Resource]
+	if !ok {
+		res, params, found := r.match(req.Path)
+		if !found {
+			return notFound
+		}
+		methods = r.routes[res]
+		req.Resource = res
+		if req.PathParameters == nil {
+			req.PathParameters = make(map[string]string, len(params))
+		}
+		for k, v := range params {
+			req.PathParameters[k] = v
+		}
+	}
+
+	if h, ok := methods[req.HTTPMethod]; ok {
+		return h
+	}
+	return methodNotAllowed(methods)
+}
+
+// match returns the resource path template matching the path, with the path parameters.
+func (r *Router) match(path string) (string, map[string]string, bool) {
+	segments := splitPath(path)
+	for _, res := range r.resources {
+		if params, ok := res.match(segments); ok {
+			return res.template, params, true
+		}
+	}
+	return "", nil, false
+}
+
+// match returns the path parameters of the path segments, if they match the resource.
+// A greedy {name+} parameter matches the rest of the path.
+func (res resource) match(segments []string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for i, s := range res.segments {
+		if isParam(s) && strings.HasSuffix(s, "+}") {
+			if i >= len(segments) {
+				return nil, false
+			}
+			params[s[1:len(s)-2]] = unescape(strings.Join(segments[i:], "/"))
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		switch {
+		case isParam(s):
+			params[s[1:len(s)-1]] = unescape(segments[i])
+		case s != segments[i]:
+			return nil, false
+		}
+	}
+	return params, len(segments) == len(res.segments)
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// unescape decodes an escaped path segment, e.g. a timestamp with %3A.
+func unescape(segment string) string {
+	if s, err := url.PathUnescape(segment); err == nil {
+		return s
+	}
+	return segment
+}
+
+// notFound responds to requests without a route.
+func notFound(
+	_ context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	return handleErrorCode(http.StatusNotFound, "no route for %s %s", req.HTTPMethod, req.Path)
+}
+
+// methodNotAllowed returns a handler responding to requests with a method
+// the resource does not allow, listing the allowed methods.
+func methodNotAllowed(methods map[string]HandlerFunc) HandlerFunc {
+	allowed := make([]string, 0, len(methods))
+	for m := range methods {
+		allowed = append(allowed, m)
+	}
+	sort.Strings(allowed)
+
+	return func(
+		_ context.Context,
+		req events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		res, err := handleProblem(
+			http.StatusMethodNotAllowed,
+			fmt.Errorf("method %s not allowed on %s", req.HTTPMethod, req.Resource),
+		)
+		if err == nil {
+			res.Headers["Allow"] = strings.Join(allowed, ", ")
+		}
+		return res, err
+	}
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// echo responds with the resource and the path parameters of the request.
+func echo(
+	_ context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	return handleOK(map[string]interface{}{
+		"resource": req.Resource,
+		"params":   req.PathParameters,
+	})
+}
+
+func TestRouter(t *testing.T) {
+	r := NewRouter()
+	r.Handle(http.MethodGet, "/transactions/{user_id}/{ts}", echo)
+	r.Handle(http.MethodPost, "/transactions", echo)
+	r.Handle(http.MethodPut, "/users/{user_id}/rules/{rule_id}", echo)
+	r.Handle(http.MethodPost, "/users/{user_id}/rules/apply", echo)
+	r.Handle(http.MethodGet, "/files/{key+}", echo)
+
+	tests := []struct {
+		name         string
+		req          events.APIGatewayProxyRequest
+		wantStatus   int
+		wantResource string
+		wantParams   map[string]string
+	}{
+		{
+			name: "resource",
+			req: events.APIGatewayProxyRequest{
+				HTTPMethod:     http.MethodGet,
+				Resource:       "/transactions/{user_id}/{ts}",
+				Path:           "/transactions/john/2024",
+				PathParameters: map[string]string{"user_id": "john", "ts": "2024"},
+			},
+			wantStatus:   http.StatusOK,
+			wantResource: "/transactions/{user_id}/{ts}",
+			wantParams:   map[string]string{"user_id": "john", "ts": "2024"},
+		},
+		{
+			name:         "path",
+			req:          events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/transactions/john/2024-01-01T10%3A00"},
+			wantStatus:   http.StatusOK,
+			wantResource: "/transactions/{user_id}/{ts}",
+			wantParams:   map[string]string{"user_id": "john", "ts": "2024-01-01T10:00"},
+		},
+		{
+			name:         "proxy resource",
+			req:          events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Resource: "/{proxy+}", Path: "/transactions/"},
+			wantStatus:   http.StatusOK,
+			wantResource: "/transactions",
+			wantParams:   map[string]string{},
+		},
+		{
+			name:         "literal before parameter",
+			req:          events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/users/john/rules/apply"},
+			wantStatus:   http.StatusOK,
+			wantResource: "/users/{user_id}/rules/apply",
+			wantParams:   map[string]string{"user_id": "john"},
+		},
+		{
+			name:         "greedy parameter",
+			req:          events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/files/a/b.csv"},
+			wantStatus:   http.StatusOK,
+			wantResource: "/files/{key+}",
+			wantParams:   map[string]string{"key": "a/b.csv"},
+		},
+		{
+			name:       "not found",
+			req:        events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/transactions/john"},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "method not allowed",
+			req:        events.APIGatewayProxyRequest{HTTPMethod: http.MethodDelete, Path: "/transactions"},
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := r.Serve(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("unexpected status %d, want %d: %s", res.StatusCode, tt.wantStatus, res.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if res.Headers["Content-Type"] != ProblemContentType {
+					t.Errorf("expected a problem response, got %v", res.Headers)
+				}
+				return
+			}
+
+			var body struct {
+				Resource string            `json:"resource"`
+				Params   map[string]string `json:"params"`
+			}
+			if err := json.Unmarshal([]byte(res.Body), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Resource != tt.wantResource {
+				t.Errorf("unexpected resource %q, want %q", body.Resource, tt.wantResource)
+			}
+			if body.Params == nil {
+				body.Params = map[string]string{}
+			}
+			if !reflect.DeepEqual(body.Params, tt.wantParams) {
+				t.Errorf("unexpected path parameters %v, want %v", body.Params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	r := NewRouter()
+	r.Handle(http.MethodPut, "/users/{user_id}/rules/{rule_id}", echo)
+	r.Handle(http.MethodDelete, "/users/{user_id}/rules/{rule_id}", echo)
+
+	res, _ := r.Serve(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Resource:   "/users/{user_id}/rules/{rule_id}",
+	})
+	if res.StatusCode != http.StatusMethodNotAllowed || res.Headers["Allow"] != "DELETE, PUT" {
+		t.Errorf("unexpected response %d with headers %v", res.StatusCode, res.Headers)
+	}
+}
+
+func TestRouter_Middleware(t *testing.T) {
+	var calls []string
+	mark := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(
+				ctx context.Context,
+				req events.APIGatewayProxyRequest,
+			) (events.APIGatewayProxyResponse, error) {
+				calls = append(calls, name+" "+req.Resource)
+				return next(ctx, req)
+			}
+		}
+	}
+	r := NewRouter(mark("outer"), mark("inner"))
+	r.Handle(http.MethodPost, "/transactions", echo)
+
+	if _, err := r.Serve(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/transactions",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"outer /transactions", "inner /transactions"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("unexpected calls %v, want %v", calls, want)
+	}
+}
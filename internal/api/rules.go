@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+)
+
+// decodeRule decodes the rule of the request body for the user of the request path.
+func decodeRule(req events.APIGatewayProxyRequest) (db.Rule, error) {
+	var r db.Rule
+
+	dec := json.NewDecoder(strings.NewReader(req.Body))
+	if err := dec.Decode(&r); err != nil {
+		return db.Rule{}, err
+	}
+	r.UserID = req.PathParameters["user_id"]
+	return r, nil
+}
+
+// handleListRules handles GET /users/{user_id}/rules requests.
+func handleListRules(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	rules, err := client.Rules(ctx, req.PathParameters["user_id"])
+	if err != nil {
+		return handleError("failed to list rules: %w", err)
+	}
+	if rules == nil {
+		rules = db.Rules{}
+	}
+
+	return handleOK(mapper(req).Item(rules))
+}
+
+// handleCreateRule handles POST /users/{user_id}/rules requests.
+func handleCreateRule(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	r, err := decodeRule(req)
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+	}
+	r.ID = ""
+
+	ctx, cancel := context.WithTimeout(ctx, INSERT_TIMEOUT)
+	defer cancel()
+	if err := client.PutRule(ctx, &r); err != nil {
+		return handleError("failed to create rule: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(r))
+}
+
+// handleUpdateRule handles PUT /users/{user_id}/rules/{rule_id} requests.
+func handleUpdateRule(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	r, err := decodeRule(req)
+	if err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+	}
+	r.ID = req.PathParameters["rule_id"]
+
+	ctx, cancel := context.WithTimeout(ctx, INSERT_TIMEOUT)
+	defer cancel()
+	if err := client.PutRule(ctx, &r); err != nil {
+		return handleError("failed to update rule: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(r))
+}
+
+// handleDeleteRule handles DELETE /users/{user_id}/rules/{rule_id} requests.
+func handleDeleteRule(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, INSERT_TIMEOUT)
+	defer cancel()
+
+	err := client.DeleteRule(ctx, req.PathParameters["user_id"], req.PathParameters["rule_id"])
+	if err != nil {
+		return handleError("failed to delete rule: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(struct{}{}))
+}
+
+// handleApplyRules handles POST /users/{user_id}/rules/apply requests.
+// It re-applies the rules to the transactions matching the optional ts prefix
+// query parameter. Existing categories are replaced if overwrite=true.
+// Large histories should be processed with the apply-rules command instead.
+func handleApplyRules(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	report, err := client.ReapplyRules(ctx, db.UserListRequest{
+		UserID:          req.PathParameters["user_id"],
+		TimestampPrefix: req.QueryStringParameters["ts"],
+	}, req.QueryStringParameters["overwrite"] == "true")
+	if err != nil {
+		return handleError("failed to apply rules: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(report))
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+)
+
+// handleSearch handles GET /users/{user_id}/search?q= requests.
+// Words of q match whole tokens of the description and counterparty,
+// words ending with * match token prefixes.
+func handleSearch(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	limit := 0
+	if s := req.QueryStringParameters["limit"]; s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil {
+			return handleErrorCode(http.StatusBadRequest, "failed to parse limit query param: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LIST_TIMEOUT)
+	defer cancel()
+
+	trs, err := client.Search(ctx, req.PathParameters["user_id"], req.QueryStringParameters["q"], limit)
+	if err != nil {
+		return handleError("failed to search: %w", err)
+	}
+
+	return handleEncoded(req, mapper(req).List(req, db.ListResponse{Items: trs}))
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DefaultChangedBy is recorded as the author of status changes of requests
+// without changed_by nor caller identity.
+const DefaultChangedBy = "api"
+
+// callerIdentity returns the identity of the caller of the request, DefaultChangedBy if unknown.
+func callerIdentity(req events.APIGatewayProxyRequest) string {
+	if arn := req.RequestContext.Identity.UserArn; arn != "" {
+		return arn
+	}
+	return DefaultChangedBy
+}
+
+// handleTransition handles POST /users/{user_id}/transactions/{ts}/status requests.
+// The body {"status": "posted", "changed_by": "ops"} names the new status and who changes it.
+// If changed_by is omitted the caller identity of the request is recorded,
+// DefaultChangedBy when there is none, as under API key authentication.
+func handleTransition(
+	ctx context.Context,
+	req events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	var body struct {
+		Status    string `json:"status"`
+		ChangedBy string `json:"changed_by"`
+	}
+
+	dec := json.NewDecoder(strings.NewReader(req.Body))
+	if err := dec.Decode(&body); err != nil {
+		return handleErrorCode(http.StatusBadRequest, "failed to decode request body: %w", err)
+	}
+	if body.ChangedBy == "" {
+		body.ChangedBy = callerIdentity(req)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, INSERT_TIMEOUT)
+	defer cancel()
+
+	tr, err := client.TransitionStatus(ctx, transactionPK(req), body.Status, body.ChangedBy)
+	if err != nil {
+		return handleError("failed to change status: %w", err)
+	}
+
+	return handleOK(mapper(req).Item(tr))
+}
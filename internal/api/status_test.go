@@ -0,0 +1,20 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestCallerIdentity(t *testing.T) {
+	req := events.APIGatewayProxyRequest{}
+	if got := callerIdentity(req); got != DefaultChangedBy {
+		t.Errorf("expected %s without caller identity, got %s", DefaultChangedBy, got)
+	}
+
+	arn := "arn:aws:iam::123456789012:user/ops"
+	req.RequestContext.Identity.UserArn = arn
+	if got := callerIdentity(req); got != arn {
+		t.Errorf("expected %s, got %s", arn, got)
+	}
+}
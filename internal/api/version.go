@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+)
+
+// API versions. Routes are served unversioned, e.g. /transactions, and under
+// the prefix of each version, e.g. /v2/transactions.
+const (
+	V1 = "v1"
+	V2 = "v2"
+)
+
+// DefaultVersion is the version of unversioned requests without version header,
+// the shapes consumers relied on before versioning.
+const DefaultVersion = V1
+
+// VersionHeader is the request header selecting the version of unversioned
+// routes, and the response header with the version served.
+const VersionHeader = "X-Api-Version"
+
+// ErrUnknownVersion is returned for version headers of versions the API does not serve.
+var ErrUnknownVersion = errors.New("unknown API version")
+
+// mappers are the mappers of the API versions.
+var mappers = map[string]Mapper{
+	V1: v1Mapper{},
+	V2: v2Mapper{},
+}
+
+// Mapper maps the requests and responses of an API version to and from the db
+// layer, which is shared by all versions.
+type Mapper interface {
+	// ListRequest returns the list request of the request.
+	ListRequest(req events.APIGatewayProxyRequest) (db.UserListRequest, error)
+	// CursorParam is the query parameter with the cursor of list requests.
+	CursorParam() string
+	// List returns the response body of a list of transactions for the request.
+	List(req events.APIGatewayProxyRequest, resp db.ListResponse) interface{}
+	// Item returns the response body of a single record, e.g. a transaction, and
+	// of the responses other than lists of transactions, e.g. a list of holds.
+	Item(v interface{}) interface{}
+}
+
+// requestVersion returns the version of the request: the version prefix of its
+// resource, or its version header on unversioned routes, or the default version.
+func requestVersion(req events.APIGatewayProxyRequest) (string, error) {
+	if v, _, ok := versionPrefix(req.Resource); ok {
+		return v, nil
+	}
+	v := strings.ToLower(strings.TrimSpace(header(req, VersionHeader)))
+	if v == "" {
+		return DefaultVersion, nil
+	}
+	if _, ok := mappers[v]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownVersion, v)
+	}
+	return v, nil
+}
+
+// versionPrefix splits a resource in its version prefix and the unversioned resource.
+func versionPrefix(resource string) (string, string, bool) {
+	for v := range mappers {
+		if rest := strings.TrimPrefix(resource, "/"+v); rest != resource && strings.HasPrefix(rest, "/") {
+			return v, rest, true
+		}
+	}
+	return "", resource, false
+}
+
+// mapper returns the mapper of the version of the request.
+func mapper(req events.APIGatewayProxyRequest) Mapper {
+	v, err := requestVersion(req)
+	if err != nil {
+		// rejected by the Versioning middleware
+		return mappers[DefaultVersion]
+	}
+	return mappers[v]
+}
+
+// Versioning rejects requests with an unknown version header with 400 Bad Request,
+// and sets the version served in the version header of responses.
+func Versioning(next HandlerFunc) HandlerFunc {
+	return func(
+		ctx context.Context,
+		req events.APIGatewayProxyRequest,
+	) (events.APIGatewayProxyResponse, error) {
+		v, err := requestVersion(req)
+		if err != nil {
+			return handleErrorCode(http.StatusBadRequest, "failed to select version: %w", err)
+		}
+
+		res, err := next(ctx, req)
+		if err != nil {
+			return res, err
+		}
+		setHeader(&res, VersionHeader, v)
+		if _, _, ok := versionPrefix(req.Resource); !ok {
+			// the representation of unversioned routes depends on the header
+			addVary(&res, VersionHeader)
+		}
+		return res, nil
+	}
+}
+
+// v1Mapper maps the original shapes of the API: list responses are the db list
+// responses with the links of the page, records are responded as they are.
+type v1Mapper struct{}
+
+// ListResponseV1 is the list response of version 1.
+type ListResponseV1 struct {
+	db.ListResponse
+	Links Links `json:"links"`
+}
+
+func (v1Mapper) ListRequest(req events.APIGatewayProxyRequest) (db.UserListRequest, error) {
+	return db.UserListRequestFromAPIGatewayProxyRequest(req)
+}
+
+func (v1Mapper) CursorParam() string {
+	return "after"
+}
+
+func (m v1Mapper) List(req events.APIGatewayProxyRequest, resp db.ListResponse) interface{} {
+	return ListResponseV1{ListResponse: resp, Links: pageLinks(req, resp.Cursor, m.CursorParam())}
+}
+
+func (v1Mapper) Item(v interface{}) interface{} {
+	return v
+}
+
+// v2Mapper maps the shapes of version 2: every response body is wrapped in a data
+// envelope, next to the metadata and the links of lists of transactions, and the
+// cursor of lists is the cursor query parameter.
+type v2Mapper struct{}
+
+// ListResponseV2 is the list response of version 2.
+type ListResponseV2 struct {
+	Data  []db.Transaction `json:"data"`
+	Meta  PageMeta         `json:"meta"`
+	Links Links            `json:"links"`
+}
+
+// PageMeta describes a page of a list.
+type PageMeta struct {
+	Count int `json:"count"`
+	// NextCursor is the cursor of the next page, empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ItemV2 is the response of version 2 other than a list of transactions.
+type ItemV2 struct {
+	Data interface{} `json:"data"`
+}
+
+func (m v2Mapper) ListRequest(req events.APIGatewayProxyRequest) (db.UserListRequest, error) {
+	listReq, err := db.UserListRequestFromAPIGatewayProxyRequest(req)
+	if err != nil {
+		return db.UserListRequest{}, err
+	}
+	listReq.After = req.QueryStringParameters[m.CursorParam()]
+	return listReq, nil
+}
+
+func (v2Mapper) CursorParam() string {
+	return "cursor"
+}
+
+func (m v2Mapper) List(req events.APIGatewayProxyRequest, resp db.ListResponse) interface{} {
+	items := resp.Items
+	if items == nil {
+		items = []db.Transaction{}
+	}
+	return ListResponseV2{
+		Data:  items,
+		Meta:  PageMeta{Count: len(items), NextCursor: resp.Cursor},
+		Links: pageLinks(req, resp.Cursor, m.CursorParam()),
+	}
+}
+
+func (v2Mapper) Item(v interface{}) interface{} {
+	return ItemV2{Data: v}
+}
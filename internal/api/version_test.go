@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"transactions/internal/db"
+)
+
+func TestRequestVersion(t *testing.T) {
+	tests := []struct {
+		resource string
+		header   string
+		want     string
+		err      error
+	}{
+		{resource: "/transactions", want: V1},
+		{resource: "/transactions", header: "v2", want: V2},
+		{resource: "/transactions", header: " V2 ", want: V2},
+		{resource: "/v2/transactions", want: V2},
+		{resource: "/v1/transactions", header: "v2", want: V1},
+		{resource: "/v2x/transactions", want: V1},
+		{resource: "/transactions", header: "v3", err: ErrUnknownVersion},
+	}
+	for _, tt := range tests {
+		req := events.APIGatewayProxyRequest{
+			Resource: tt.resource,
+			Headers:  map[string]string{VersionHeader: tt.header},
+		}
+		got, err := requestVersion(req)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("requestVersion(%q, %q) error = %v, want %v", tt.resource, tt.header, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("requestVersion(%q, %q) = %s, want %s", tt.resource, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	r := NewRouter(Versioning)
+	for _, template := range []string{"/transactions/{user_id}/{ts}", "/v1/transactions/{user_id}/{ts}", "/v2/transactions/{user_id}/{ts}"} {
+		r.Handle(http.MethodGet, template, echo)
+	}
+
+	tests := []struct {
+		path     string
+		header   string
+		resource string
+		version  string
+		vary     string
+		status   int
+	}{
+		{"/transactions/john/2024", "", "/transactions/{user_id}/{ts}", V1, VersionHeader, http.StatusOK},
+		{"/transactions/john/2024", "v2", "/transactions/{user_id}/{ts}", V2, VersionHeader, http.StatusOK},
+		{"/v2/transactions/john/2024", "", "/v2/transactions/{user_id}/{ts}", V2, "", http.StatusOK},
+		{"/v1/transactions/john/2024", "", "/v1/transactions/{user_id}/{ts}", V1, "", http.StatusOK},
+		{"/transactions/john/2024", "v9", "", "", "", http.StatusBadRequest},
+		{"/v9/transactions/john/2024", "", "", V1, VersionHeader, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		res, err := r.Serve(context.Background(), events.APIGatewayProxyRequest{
+			HTTPMethod: http.MethodGet,
+			Path:       tt.path,
+			Headers:    map[string]string{VersionHeader: tt.header},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != tt.status || res.Headers[VersionHeader] != tt.version || res.Headers["Vary"] != tt.vary {
+			t.Errorf("%s (%s): unexpected response %d %v", tt.path, tt.header, res.StatusCode, res.Headers)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal([]byte(res.Body), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["resource"] != tt.resource {
+			t.Errorf("%s: expected resource %s, got %v", tt.path, tt.resource, body["resource"])
+		}
+	}
+}
+
+func TestMappers(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Resource:              "/transactions/{user_id}/{ts}",
+		PathParameters:        map[string]string{"user_id": "john", "ts": "2024"},
+		QueryStringParameters: map[string]string{"after": "a", "cursor": "c"},
+	}
+	if listReq, err := mappers[V1].ListRequest(req); err != nil || listReq.After != "a" {
+		t.Errorf("v1 list request has cursor %q, error %v", listReq.After, err)
+	}
+	if listReq, err := mappers[V2].ListRequest(req); err != nil || listReq.After != "c" {
+		t.Errorf("v2 list request has cursor %q, error %v", listReq.After, err)
+	}
+
+	resp := db.ListResponse{Items: []db.Transaction{{UserID: "john", ID: "a"}}, Cursor: "next"}
+	tests := []struct {
+		version string
+		body    interface{}
+		want    string
+	}{
+		{V1, mappers[V1].List(req, resp), `{"items":[{"user_id":"john","ts"`},
+		{V2, mappers[V2].List(req, resp), `{"data":[{"user_id":"john","ts"`},
+		{V2, mappers[V2].List(req, db.ListResponse{}), `{"data":[],"meta":{"count":0},"links"`},
+		{V1, mappers[V1].Item(resp.Items[0]), `{"user_id":"john","ts"`},
+		{V2, mappers[V2].Item(resp.Items[0]), `{"data":{"user_id":"john","ts"`},
+		{V1, mappers[V1].Item([]db.Hold{{UserID: "john"}}), `[{"user_id":"john","hold_id"`},
+		{V2, mappers[V2].Item([]db.Hold{{UserID: "john"}}), `{"data":[{"user_id":"john","hold_id"`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(b), tt.want) {
+			t.Errorf("%s: expected body starting with %s, got %s", tt.version, tt.want, b)
+		}
+	}
+
+	b, _ := json.Marshal(mappers[V1].List(req, resp))
+	if !strings.HasSuffix(string(b), `"cursor":"next","links":{"self":"/transactions/john/2024?after=a\u0026cursor=c","next":"/transactions/john/2024?after=next\u0026cursor=c"}}`) {
+		t.Errorf("unexpected v1 list cursor and links %s", b)
+	}
+
+	b, _ = json.Marshal(mappers[V2].List(req, resp))
+	if !strings.Contains(string(b), `"meta":{"count":1,"next_cursor":"next"}`) {
+		t.Errorf("unexpected v2 list metadata %s", b)
+	}
+
+	rows, err := transactionRows(mappers[V2].Item(db.TransactionDetails{Transaction: resp.Items[0]}))
+	if err != nil || len(rows) != 1 {
+		t.Errorf("expected the v2 transaction row, got %v, %v", rows, err)
+	}
+}
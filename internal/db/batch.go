@@ -0,0 +1,266 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxTransactItems is the maximum number of items of a TransactWriteItems request.
+const maxTransactItems = 100
+
+// CreateBatch creates transactions. Like Create, the rules of the users are
+// applied to assign categories and tags unless they are given, and the duplicate
+// policy applies against the transactions already written and the transactions
+// of the batch preceding them. The duplicates are looked for with a query per
+// time window of the batch, and the transactions are written with conditional
+// puts in transactions of up to maxTransactItems items, as batch writes would
+// overwrite existing transactions: a transaction with the primary key of an
+// existing one fails with ErrTransactionExists. The returned slice holds the
+// error of every transaction that failed validation, was a rejected duplicate
+// or exists and was not written, nil for the written ones.
+func (c *Client) CreateBatch(ctx context.Context, trs []Transaction) ([]error, error) {
+	errs := make([]error, len(trs))
+	rules := map[string]Rules{}
+
+	for i := range trs {
+		t := &trs[i]
+		if errs[i] = t.validateNew(); errs[i] != nil {
+			continue
+		}
+
+		userRules, ok := rules[t.UserID]
+		if !ok {
+			var err error
+			if userRules, err = c.Rules(ctx, t.UserID); err != nil {
+				return nil, fmt.Errorf("failed to get rules: %w", err)
+			}
+			rules[t.UserID] = userRules
+		}
+		errs[i] = c.categorize(t, userRules)
+	}
+
+	if c.duplicates.Mode != DuplicateAllow {
+		if err := c.checkDuplicates(ctx, trs, errs); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, chunk := range writeChunks(trs, errs) {
+		if err := c.putChunk(ctx, trs, chunk, errs); err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
+
+// checkDuplicates applies the duplicate policy to the transactions of a batch
+// without errors, as checkDuplicate does to a transaction. A transaction may
+// duplicate a transaction written before or a transaction of the batch preceding it.
+func (c *Client) checkDuplicates(ctx context.Context, trs []Transaction, errs []error) error {
+	times := map[string][]time.Time{}
+	for i, t := range trs {
+		if ts, err := time.Parse(TimestampLayout, t.Timestamp); err == nil && errs[i] == nil {
+			times[t.UserID] = append(times[t.UserID], ts)
+		}
+	}
+
+	candidates := map[string][]Transaction{}
+	for userID, userTimes := range times {
+		for _, w := range c.duplicates.windows(userTimes) {
+			found, err := c.queryWindow(ctx, userID, w)
+			if err != nil {
+				return fmt.Errorf("failed to find duplicates: %w", err)
+			}
+			candidates[userID] = append(candidates[userID], found...)
+		}
+	}
+
+	for i := range trs {
+		t := &trs[i]
+		if errs[i] != nil {
+			continue
+		}
+		if dup := c.duplicates.find(*t, candidates[t.UserID]); dup != nil {
+			if c.duplicates.Mode == DuplicateReject {
+				errs[i] = &DuplicateError{Of: *dup}
+				continue
+			}
+			t.DuplicateOf = dup
+		}
+		candidates[t.UserID] = append(candidates[t.UserID], *t)
+	}
+	return nil
+}
+
+// window is a range of timestamps, both bounds included.
+type window struct {
+	From, To time.Time
+}
+
+// windows returns the ranges of the timestamps the duplicates of transactions
+// with the timestamps may have, overlapping ranges being merged.
+func (p DuplicatePolicy) windows(times []time.Time) []window {
+	sorted := append([]time.Time(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	var ws []window
+	for _, ts := range sorted {
+		from, to := ts.Add(-p.Window), ts.Add(p.Window)
+		if n := len(ws); n > 0 && !from.After(ws[n-1].To) {
+			ws[n-1].To = to
+			continue
+		}
+		ws = append(ws, window{From: from, To: to})
+	}
+	return ws
+}
+
+// queryWindow returns the transactions of a user in the window.
+func (c *Client) queryWindow(ctx context.Context, userID string, w window) ([]Transaction, error) {
+	keyCond := expression.Key("user_id").Equal(expression.Value(userID)).And(
+		expression.Key("ts").Between(
+			expression.Value(w.From.Format(TimestampLayout)),
+			expression.Value(w.To.Format(TimestampLayout)),
+		),
+	)
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var trs []Transaction
+	paginator := dynamodb.NewQueryPaginator(c.c, &dynamodb.QueryInput{
+		TableName:                 aws.String(c.table),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for paginator.HasMorePages() {
+		res, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var page []Transaction
+		if err := attributevalue.UnmarshalListOfMaps(res.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode transactions: %w", err)
+		}
+		trs = append(trs, page...)
+	}
+	return trs, nil
+}
+
+// find returns the primary key of the first of the candidates duplicated by
+// the transaction, nil if there is none, with the criteria of FindDuplicate.
+func (p DuplicatePolicy) find(t Transaction, candidates []Transaction) *TransactionPK {
+	ts, err := time.Parse(TimestampLayout, t.Timestamp)
+	if err != nil {
+		return nil
+	}
+	for _, c := range candidates {
+		cts, err := time.Parse(TimestampLayout, c.Timestamp)
+		if err != nil || cts.Before(ts.Add(-p.Window)) || cts.After(ts.Add(p.Window)) {
+			continue
+		}
+		if c.UserID == t.UserID && c.Amount == t.Amount && c.OperationType == t.OperationType &&
+			c.Origin == t.Origin && c.ExternalID == t.ExternalID {
+			pk := c.PK()
+			return &pk
+		}
+	}
+	return nil
+}
+
+// writeChunks returns the indexes of the transactions without errors in chunks
+// written by a TransactWriteItems request each, a transaction being written with
+// its tags. A transaction with the primary key of a transaction of the batch
+// preceding it fails with ErrTransactionExists, as a request cannot write an item twice.
+func writeChunks(trs []Transaction, errs []error) [][]int {
+	var (
+		chunks [][]int
+		chunk  []int
+		items  int
+	)
+	keys := map[TransactionPK]bool{}
+	for i, t := range trs {
+		if errs[i] != nil {
+			continue
+		}
+		if keys[t.PK()] {
+			errs[i] = transactionExists(t.PK())
+			continue
+		}
+		keys[t.PK()] = true
+
+		n := 1 + len(t.Tags)
+		if items+n > maxTransactItems {
+			chunks = append(chunks, chunk)
+			chunk, items = nil, 0
+		}
+		chunk = append(chunk, i)
+		items += n
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// putChunk writes the transactions of a chunk and their tags in a transaction.
+// When existing transactions cancel it, they fail with ErrTransactionExists and
+// the others are written again.
+func (c *Client) putChunk(ctx context.Context, trs []Transaction, chunk []int, errs []error) error {
+	for len(chunk) > 0 {
+		var (
+			writes []types.TransactWriteItem
+			// owners are the indexes of the transactions of the writes
+			owners []int
+		)
+		for _, i := range chunk {
+			w, err := c.newWrites(trs[i])
+			if err != nil {
+				return err
+			}
+			writes = append(writes, w...)
+			for range w {
+				owners = append(owners, i)
+			}
+		}
+
+		_, err := c.c.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: writes,
+		})
+		var canceled *types.TransactionCanceledException
+		if !errors.As(err, &canceled) {
+			return err
+		}
+		exists := map[int]bool{}
+		for j, reason := range canceled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" && j < len(owners) {
+				exists[owners[j]] = true
+			}
+		}
+		if len(exists) == 0 {
+			return err
+		}
+
+		var rest []int
+		for _, i := range chunk {
+			if exists[i] {
+				errs[i] = transactionExists(trs[i].PK())
+			} else {
+				rest = append(rest, i)
+			}
+		}
+		chunk = rest
+	}
+	return nil
+}
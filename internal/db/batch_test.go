@@ -0,0 +1,101 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDuplicatePolicy_Windows(t *testing.T) {
+	p := DuplicatePolicy{Mode: DuplicateFlag, Window: 10 * time.Minute}
+	at := func(s string) time.Time {
+		ts, err := time.Parse(TimestampLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	got := p.windows([]time.Time{
+		at("2024-01-02T12:00:00Z"),
+		at("2024-01-02T10:00:00Z"),
+		at("2024-01-02T10:15:00Z"),
+	})
+	want := []window{
+		{From: at("2024-01-02T09:50:00Z"), To: at("2024-01-02T10:25:00Z")},
+		{From: at("2024-01-02T11:50:00Z"), To: at("2024-01-02T12:10:00Z")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("windows() = %v, want %v", got, want)
+	}
+}
+
+func TestDuplicatePolicy_Find(t *testing.T) {
+	p := DuplicatePolicy{Mode: DuplicateFlag, Window: 10 * time.Minute}
+	tr := Transaction{
+		UserID:        "john",
+		Timestamp:     "2024-01-02T10:00:00Z",
+		Origin:        "import",
+		OperationType: OperationDebit,
+		Amount:        12.5,
+		ExternalID:    "A1",
+	}
+	at := func(ts string) Transaction {
+		c := tr
+		c.Timestamp = ts
+		return c
+	}
+	other := at("2024-01-02T10:01:00Z")
+	other.Amount = 13
+
+	tests := []struct {
+		name       string
+		candidates []Transaction
+		want       *TransactionPK
+	}{
+		{"none", nil, nil},
+		{"same key", []Transaction{tr}, &TransactionPK{UserID: "john", Timestamp: "2024-01-02T10:00:00Z"}},
+		{"within window", []Transaction{other, at("2024-01-02T10:05:00Z")}, &TransactionPK{UserID: "john", Timestamp: "2024-01-02T10:05:00Z"}},
+		{"outside window", []Transaction{at("2024-01-02T10:11:00Z")}, nil},
+		{"other amount", []Transaction{other}, nil},
+	}
+	for _, tt := range tests {
+		if got := p.find(tr, tt.candidates); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: find() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteChunks(t *testing.T) {
+	at := func(userID string, i int) Transaction {
+		return Transaction{UserID: userID, Timestamp: time.Date(2024, 1, 2, 0, 0, i, 0, time.UTC).Format(TimestampLayout)}
+	}
+	trs := make([]Transaction, 60)
+	for i := range trs {
+		trs[i] = at("john", i)
+	}
+	trs[0].Tags = []string{"a", "b"}
+	trs[5].Timestamp = trs[4].Timestamp
+	errs := make([]error, len(trs))
+	errs[1] = ErrInvalidRange
+
+	chunks := writeChunks(trs, errs)
+	if !errors.Is(errs[5], ErrTransactionExists) {
+		t.Errorf("expected the second transaction with a key to exist, got %v", errs[5])
+	}
+	// 58 transactions and 2 tags make 60 items, written in a chunk
+	if len(chunks) != 1 || len(chunks[0]) != 58 {
+		t.Fatalf("unexpected chunks %v", chunks)
+	}
+
+	trs = append(trs, make([]Transaction, 50)...)
+	for i := 60; i < len(trs); i++ {
+		trs[i] = at("jane", i)
+	}
+	errs = append(errs, make([]error, 50)...)
+	chunks = writeChunks(trs, errs)
+	if len(chunks) != 2 || len(chunks[0]) != 98 || len(chunks[1]) != 10 {
+		t.Errorf("expected chunks of 98 and 10 transactions, got %d chunks", len(chunks))
+	}
+}
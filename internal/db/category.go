@@ -0,0 +1,75 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownCategory is returned when a transaction uses a category missing from the registry.
+var ErrUnknownCategory = invalid("unknown category")
+
+// DefaultCategories are the categories known when no registry is configured.
+var DefaultCategories = []string{
+	"groceries",
+	"household",
+	"dining",
+	"transport",
+	"travel",
+	"utilities",
+	"rent",
+	"health",
+	"entertainment",
+	"shopping",
+	"education",
+	"salary",
+	"transfer",
+	"fees",
+	"other",
+}
+
+// Categories is a registry of the categories transactions and splits may use.
+type Categories map[string]bool
+
+// NewCategories creates a registry of the given categories.
+func NewCategories(names ...string) Categories {
+	c := make(Categories, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			c[name] = true
+		}
+	}
+	return c
+}
+
+// CategoriesFromEnv creates a registry from the comma separated CATEGORIES
+// environment variable, or of the default categories if it is not set.
+func CategoriesFromEnv() Categories {
+	if names := getenv("CATEGORIES", ""); names != "" {
+		return NewCategories(strings.Split(names, ",")...)
+	}
+	return NewCategories(DefaultCategories...)
+}
+
+// Names returns the sorted category names.
+func (c Categories) Names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the categories of the transaction and its splits are registered.
+func (c Categories) Validate(tr Transaction) error {
+	if tr.Category != "" && !c[tr.Category] {
+		return invalidField("category", fmt.Errorf("%w: %s", ErrUnknownCategory, tr.Category))
+	}
+	for i, s := range tr.Splits {
+		if !c[s.Category] {
+			return invalidField(fmt.Sprintf("splits[%d].category", i), fmt.Errorf("%w: %s", ErrUnknownCategory, s.Category))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCategories_Validate(t *testing.T) {
+	categories := NewCategories("groceries", " household ", "")
+
+	tests := []struct {
+		name string
+		tr   Transaction
+		err  error
+	}{
+		{"uncategorized", Transaction{}, nil},
+		{"known category", Transaction{Category: "groceries"}, nil},
+		{"trimmed category", Transaction{Category: "household"}, nil},
+		{"unknown category", Transaction{Category: "casino"}, ErrUnknownCategory},
+		{"known split categories", Transaction{Splits: []Split{
+			{Category: "groceries"}, {Category: "household"},
+		}}, nil},
+		{"unknown split category", Transaction{Category: "groceries", Splits: []Split{
+			{Category: "casino"},
+		}}, ErrUnknownCategory},
+	}
+
+	for _, test := range tests {
+		if err := categories.Validate(test.tr); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+		}
+	}
+
+	if names := categories.Names(); len(names) != 2 || names[0] != "groceries" || names[1] != "household" {
+		t.Errorf("unexpected names: %v", names)
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -13,46 +14,245 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go/middleware"
 )
 
+// ErrTransactionNotFound is returned when a transaction does not exist.
+var ErrTransactionNotFound = notFound("transaction not found")
+
 // Client represents a DynamoDB client to create and fetch transactions
 type Client struct {
-	c     *dynamodb.Client
-	table string
+	c       *dynamodb.Client
+	table   string
+	holds   string
+	tags    string
+	rules   string
+	search  string
+	exports string
+
+	categories Categories
+	duplicates DuplicatePolicy
 }
 
-// Create creates a transaction
+// Create creates a transaction. The rules of the user are applied
+// to assign a category and tags unless they are given, and duplicates
+// are rejected or flagged according to the duplicate policy.
 func (c *Client) Create(ctx context.Context, t *Transaction) error {
-	t.SetDefaults()
+	if err := t.validateNew(); err != nil {
+		return err
+	}
 
-	if err := t.Validate(); err != nil {
+	rules, err := c.Rules(ctx, t.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get rules: %w", err)
+	}
+	if err := c.categorize(t, rules); err != nil {
+		return err
+	}
+	if err := c.checkDuplicate(ctx, t); err != nil {
 		return err
 	}
 
-	av, err := attributevalue.MarshalMap(t)
+	return c.putNew(ctx, *t)
+}
+
+// ErrTransactionExists is returned when a transaction to create has the primary
+// key of an existing transaction, which is not overwritten.
+var ErrTransactionExists = conflict("transaction already exists")
+
+// putNew writes a new transaction together with its tags. The transaction is only
+// written when no transaction has its primary key, otherwise ErrTransactionExists
+// is returned. Whether the existing transaction is a duplicate is up to the
+// duplicate policy, see checkDuplicate.
+func (c *Client) putNew(ctx context.Context, t Transaction) error {
+	if len(t.Tags) == 0 {
+		av, err := attributevalue.MarshalMap(t)
+		if err != nil {
+			return err
+		}
+		_, err = c.c.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(c.table),
+			Item:                av,
+			ConditionExpression: aws.String(newCondition),
+		})
+		return existsError(t.PK(), err)
+	}
+
+	writes, err := c.newWrites(t)
 	if err != nil {
 		return err
 	}
-	_, err = c.c.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(c.table),
-		Item:      av,
+	_, err = c.c.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: writes,
 	})
+	return existsError(t.PK(), err)
+}
+
+// newCondition is the condition of the puts of new transactions.
+const newCondition = "attribute_not_exists(ts)"
+
+// newWrites returns the writes of a new transaction: the conditional put of the
+// transaction, first, followed by the puts of its tags.
+func (c *Client) newWrites(t Transaction) ([]types.TransactWriteItem, error) {
+	av, err := attributevalue.MarshalMap(t)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := c.tagWrites(t)
+	if err != nil {
+		return nil, err
+	}
+	return append([]types.TransactWriteItem{{
+		Put: &types.Put{TableName: aws.String(c.table), Item: av, ConditionExpression: aws.String(newCondition)},
+	}}, tags...), nil
+}
 
+// existsError returns ErrTransactionExists for the primary key when a conditional
+// put of a new transaction failed as it exists, err otherwise.
+func existsError(pk TransactionPK, err error) error {
+	var (
+		failed   *types.ConditionalCheckFailedException
+		canceled *types.TransactionCanceledException
+	)
+	switch {
+	case errors.As(err, &failed):
+		return transactionExists(pk)
+	case errors.As(err, &canceled):
+		// only the put of the transaction has a condition
+		for _, reason := range canceled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return transactionExists(pk)
+			}
+		}
+	}
 	return err
 }
 
+// transactionExists returns ErrTransactionExists for the primary key.
+func transactionExists(pk TransactionPK) error {
+	return fmt.Errorf("%w: %s/%s", ErrTransactionExists, pk.UserID, pk.Timestamp)
+}
+
+// validateNew sets the defaults of a transaction to create and validates it.
+// The fields maintained by the client are cleared, and refunds are rejected
+// as they are created by Refund.
+func (t *Transaction) validateNew() error {
+	t.StatusHistory = nil
+	t.RefundOf = nil
+	t.RefundedAmount = 0
+	t.RefundKeys = nil
+	t.DuplicateOf = nil
+	t.SetDefaults()
+
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if t.OperationType == OperationRefund {
+		return ErrCreateRefund
+	}
+	if t.Status != StatusPending && t.Status != StatusPosted {
+		return fmt.Errorf("%w: transactions are created %s or %s", ErrIllegalTransition, StatusPending, StatusPosted)
+	}
+	return nil
+}
+
+// categorize applies the rules to a transaction to create and validates its category.
+func (c *Client) categorize(t *Transaction, rules Rules) error {
+	if rules.Apply(t, false) {
+		t.SetDefaults()
+	}
+	return c.categories.Validate(*t)
+}
+
+// Get fetches a transaction by its primary key
+func (c *Client) Get(ctx context.Context, pk TransactionPK) (Transaction, error) {
+	res, err := c.c.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.table),
+		Key:            pk.ToAttributes(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Transaction{}, err
+	}
+	if res.Item == nil {
+		return Transaction{}, ErrTransactionNotFound
+	}
+
+	var t Transaction
+	if err := attributevalue.UnmarshalMap(res.Item, &t); err != nil {
+		return Transaction{}, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	t.SetDefaults()
+	return t, nil
+}
+
+// batchGetSize is the maximum number of keys of a BatchGetItem request.
+const batchGetSize = 100
+
+// BatchGet fetches the transactions with the given primary keys, in the order of the keys.
+// Keys of transactions that do not exist are skipped.
+func (c *Client) BatchGet(ctx context.Context, pks []TransactionPK) ([]Transaction, error) {
+	found := make(map[TransactionPK]Transaction, len(pks))
+
+	for start := 0; start < len(pks); start += batchGetSize {
+		end := start + batchGetSize
+		if end > len(pks) {
+			end = len(pks)
+		}
+
+		keys := make([]map[string]types.AttributeValue, 0, end-start)
+		for _, pk := range pks[start:end] {
+			keys = append(keys, pk.ToAttributes())
+		}
+
+		request := map[string]types.KeysAndAttributes{
+			c.table: {Keys: keys, ConsistentRead: aws.Bool(true)},
+		}
+		for len(request) > 0 {
+			res, err := c.c.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, err
+			}
+
+			var page []Transaction
+			if err := attributevalue.UnmarshalListOfMaps(res.Responses[c.table], &page); err != nil {
+				return nil, fmt.Errorf("failed to decode transactions: %w", err)
+			}
+			for _, t := range page {
+				t.SetDefaults()
+				found[t.PK()] = t
+			}
+
+			request = res.UnprocessedKeys
+		}
+	}
+
+	transactions := make([]Transaction, 0, len(found))
+	for _, pk := range pks {
+		if t, ok := found[pk]; ok {
+			transactions = append(transactions, t)
+		}
+	}
+	return transactions, nil
+}
+
 // Delete deletes a transaction
 func (c *Client) Delete(ctx context.Context, t Transaction) error {
-	_, err := c.c.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName: aws.String(c.table),
-		Key: map[string]types.AttributeValue{
-			"user_id": &types.AttributeValueMemberS{
-				Value: t.UserID,
-			},
-			"ts": &types.AttributeValueMemberS{
-				Value: t.Timestamp,
-			},
-		},
+	key := t.PK().ToAttributes()
+	if len(t.Tags) == 0 {
+		_, err := c.c.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(c.table),
+			Key:       key,
+		})
+		return err
+	}
+
+	writes := c.tagDeletes(t, t.Tags)
+	writes = append(writes, types.TransactWriteItem{
+		Delete: &types.Delete{TableName: aws.String(c.table), Key: key},
+	})
+	_, err := c.c.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: writes,
 	})
 	return err
 }
@@ -77,6 +277,39 @@ func (c *Client) Query(ctx context.Context, req UserListRequest) (ListResponse,
 	if err := req.Validate(); err != nil {
 		return ListResponse{}, err
 	}
+	return c.query(ctx, req)
+}
+
+// ForEach calls fn for every transaction matching the request, following
+// the query cursors until the last page. An empty timestamp prefix
+// matches all transactions of the user.
+func (c *Client) ForEach(
+	ctx context.Context,
+	req UserListRequest,
+	fn func(Transaction) error,
+) error {
+	for {
+		resp, err := c.query(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, t := range resp.Items {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+		if resp.Cursor == "" {
+			return nil
+		}
+		req.After = resp.Cursor
+	}
+}
+
+// query runs a single page of the request query without validating it.
+func (c *Client) query(ctx context.Context, req UserListRequest) (ListResponse, error) {
+	if req.Tag != "" {
+		return c.queryTag(ctx, req)
+	}
 
 	input, err := req.ToQueryInput(c.table)
 	if err != nil {
@@ -98,6 +331,11 @@ func (c *Client) Query(ctx context.Context, req UserListRequest) (ListResponse,
 		)
 	}
 
+	for i := range transactions {
+		// transactions created before statuses were introduced are posted
+		transactions[i].SetDefaults()
+	}
+
 	resp := ListResponse{Items: transactions}
 
 	pk := TransactionPKFromAttributes(res.LastEvaluatedKey)
@@ -128,6 +366,7 @@ func (c *Client) Scan(ctx context.Context) ([]Transaction, error) {
 func connect() *dynamodb.Client {
 	cfg, err := config.LoadDefaultConfig(
 		context.Background(),
+		config.WithAPIOptions([]func(*middleware.Stack) error{classifyErrors}),
 		config.WithRetryer(func() aws.Retryer {
 			return retry.NewStandard(
 				func(o *retry.StandardOptions) {
@@ -156,13 +395,17 @@ func connect() *dynamodb.Client {
 // NewClient creates a new DynamoDB client
 func NewClient() *Client {
 	dynamodbClient := connect()
-	tableName, _ := os.LookupEnv("TABLE_NAME")
-	if tableName == "" {
-		tableName = "Transactions"
-	}
 
 	return &Client{
-		c:     dynamodbClient,
-		table: tableName,
+		c:       dynamodbClient,
+		table:   getenv("TABLE_NAME", "Transactions"),
+		holds:   getenv("HOLDS_TABLE_NAME", "Holds"),
+		tags:    getenv("TAGS_TABLE_NAME", "TransactionTags"),
+		rules:   getenv("RULES_TABLE_NAME", "Rules"),
+		search:  getenv("SEARCH_TABLE_NAME", "SearchIndex"),
+		exports: getenv("EXPORTS_TABLE_NAME", "Exports"),
+
+		categories: CategoriesFromEnv(),
+		duplicates: DuplicatePolicyFromEnv(),
 	}
 }
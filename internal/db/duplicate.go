@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Duplicate detection modes
+const (
+	// DuplicateReject rejects duplicate transactions with a DuplicateError.
+	DuplicateReject = "reject"
+	// DuplicateFlag creates duplicate transactions referencing the transaction they duplicate.
+	DuplicateFlag = "flag"
+	// DuplicateAllow creates duplicate transactions without looking for duplicates.
+	DuplicateAllow = "allow"
+)
+
+// DefaultDuplicateWindow is how far apart in time duplicates are looked for by default.
+const DefaultDuplicateWindow = 10 * time.Minute
+
+// ErrDuplicate is returned when a transaction duplicates an existing one.
+var ErrDuplicate = conflict("duplicate transaction")
+
+// DuplicateError is returned when a transaction is rejected as a duplicate.
+type DuplicateError struct {
+	// Of is the primary key of the transaction duplicated.
+	Of TransactionPK
+}
+
+// Error returns the error message.
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("%s of transaction %s/%s", ErrDuplicate, e.Of.UserID, e.Of.Timestamp)
+}
+
+// Unwrap returns ErrDuplicate.
+func (e *DuplicateError) Unwrap() error {
+	return ErrDuplicate
+}
+
+// DuplicatePolicy configures the detection of duplicate transactions on creation.
+// Transactions of the same user with the same amount, operation type, origin and
+// external ID are duplicates when their timestamps are less than the window apart.
+type DuplicatePolicy struct {
+	Mode   string
+	Window time.Duration
+}
+
+// DuplicatePolicyFromEnv returns the policy of the DUPLICATE_MODE and
+// DUPLICATE_WINDOW environment variables, flagging duplicates within
+// DefaultDuplicateWindow by default.
+func DuplicatePolicyFromEnv() DuplicatePolicy {
+	policy := DuplicatePolicy{Mode: DuplicateFlag, Window: DefaultDuplicateWindow}
+
+	switch mode := getenv("DUPLICATE_MODE", DuplicateFlag); mode {
+	case DuplicateReject, DuplicateFlag, DuplicateAllow:
+		policy.Mode = mode
+	default:
+		log.Printf("unknown duplicate mode %q, using %s", mode, policy.Mode)
+	}
+
+	if s := getenv("DUPLICATE_WINDOW", ""); s != "" {
+		window, err := time.ParseDuration(s)
+		if err != nil || window <= 0 {
+			log.Printf("invalid duplicate window %q, using %s", s, policy.Window)
+		} else {
+			policy.Window = window
+		}
+	}
+	return policy
+}
+
+// duplicateExpression returns the expression querying the duplicates of a transaction.
+func (p DuplicatePolicy) duplicateExpression(t Transaction, ts time.Time) (expression.Expression, error) {
+	keyCond := expression.Key("user_id").Equal(expression.Value(t.UserID)).And(
+		expression.Key("ts").Between(
+			expression.Value(ts.Add(-p.Window).Format(TimestampLayout)),
+			expression.Value(ts.Add(p.Window).Format(TimestampLayout)),
+		),
+	)
+
+	externalID := expression.Name("external_id").Equal(expression.Value(t.ExternalID))
+	if t.ExternalID == "" {
+		externalID = expression.AttributeNotExists(expression.Name("external_id"))
+	}
+	filter := expression.Name("amount").Equal(expression.Value(t.Amount)).
+		And(expression.Name("operation_type").Equal(expression.Value(t.OperationType))).
+		And(expression.Name("origin").Equal(expression.Value(t.Origin))).
+		And(externalID)
+
+	return expression.NewBuilder().WithKeyCondition(keyCond).WithFilter(filter).Build()
+}
+
+// FindDuplicate returns the primary key of a transaction duplicated by the
+// transaction, nil if there is none. Transactions with timestamps in another
+// format are not checked.
+func (c *Client) FindDuplicate(ctx context.Context, t Transaction) (*TransactionPK, error) {
+	ts, err := time.Parse(TimestampLayout, t.Timestamp)
+	if err != nil {
+		return nil, nil
+	}
+
+	expr, err := c.duplicates.duplicateExpression(t, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	paginator := dynamodb.NewQueryPaginator(c.c, &dynamodb.QueryInput{
+		TableName:                 aws.String(c.table),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		FilterExpression:          expr.Filter(),
+	})
+	for paginator.HasMorePages() {
+		res, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var page []Transaction
+		if err := attributevalue.UnmarshalListOfMaps(res.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode transactions: %w", err)
+		}
+		if len(page) > 0 {
+			pk := page[0].PK()
+			return &pk, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkDuplicate applies the duplicate policy to a transaction to create:
+// a duplicate is either rejected or flagged with the key of the transaction it duplicates.
+func (c *Client) checkDuplicate(ctx context.Context, t *Transaction) error {
+	if c.duplicates.Mode == DuplicateAllow {
+		return nil
+	}
+
+	dup, err := c.FindDuplicate(ctx, *t)
+	if err != nil {
+		return fmt.Errorf("failed to find duplicates: %w", err)
+	}
+	if dup == nil {
+		return nil
+	}
+	if c.duplicates.Mode == DuplicateReject {
+		return &DuplicateError{Of: *dup}
+	}
+	t.DuplicateOf = dup
+	return nil
+}
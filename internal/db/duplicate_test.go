@@ -0,0 +1,138 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestDuplicatePolicyFromEnv(t *testing.T) {
+	tests := []struct {
+		mode, window string
+		want         DuplicatePolicy
+	}{
+		{"", "", DuplicatePolicy{Mode: DuplicateFlag, Window: DefaultDuplicateWindow}},
+		{"reject", "1h", DuplicatePolicy{Mode: DuplicateReject, Window: time.Hour}},
+		{"allow", "-1s", DuplicatePolicy{Mode: DuplicateAllow, Window: DefaultDuplicateWindow}},
+		{"ignore", "soon", DuplicatePolicy{Mode: DuplicateFlag, Window: DefaultDuplicateWindow}},
+	}
+	for _, tt := range tests {
+		setenv(t, "DUPLICATE_MODE", tt.mode)
+		setenv(t, "DUPLICATE_WINDOW", tt.window)
+		if got := DuplicatePolicyFromEnv(); got != tt.want {
+			t.Errorf("DuplicatePolicyFromEnv() with %q, %q = %+v, want %+v", tt.mode, tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestDuplicatePolicy_duplicateExpression(t *testing.T) {
+	policy := DuplicatePolicy{Mode: DuplicateFlag, Window: time.Minute}
+	tr := Transaction{
+		UserID:        "john",
+		Timestamp:     "2024-01-02T10:00:00.5Z",
+		Origin:        "web",
+		OperationType: OperationDebit,
+		Amount:        3.5,
+	}
+	ts, _ := time.Parse(TimestampLayout, tr.Timestamp)
+
+	expr, err := policy.duplicateExpression(tr, ts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := map[string]string{}
+	for key, av := range expr.Values() {
+		if v, ok := av.(*types.AttributeValueMemberS); ok {
+			values[v.Value] = key
+		}
+	}
+	for _, want := range []string{"john", "2024-01-02T09:59:00.5Z", "2024-01-02T10:01:00.5Z", "web", OperationDebit} {
+		if _, ok := values[want]; !ok {
+			t.Errorf("expected value %s, got %v", want, expr.Values())
+		}
+	}
+	if !strings.Contains(*expr.KeyCondition(), "BETWEEN") {
+		t.Errorf("expected ts between the window, got %s", *expr.KeyCondition())
+	}
+	if !strings.Contains(*expr.Filter(), "attribute_not_exists") {
+		t.Errorf("expected transactions without external ID, got %s", *expr.Filter())
+	}
+
+	tr.ExternalID = "FIT-1"
+	if expr, err = policy.duplicateExpression(tr, ts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(*expr.Filter(), "attribute_not_exists") {
+		t.Errorf("expected transactions with the external ID, got %s", *expr.Filter())
+	}
+}
+
+func TestDuplicateError(t *testing.T) {
+	var err error = &DuplicateError{Of: TransactionPK{UserID: "john", Timestamp: "2024-01-02T10:00:00Z"}}
+	if !errors.Is(fmt.Errorf("failed to create: %w", err), ErrDuplicate) {
+		t.Errorf("expected DuplicateError to be ErrDuplicate")
+	}
+	if err.Error() != "duplicate transaction of transaction john/2024-01-02T10:00:00Z" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
+
+func TestExistsError(t *testing.T) {
+	pk := TransactionPK{UserID: "john", Timestamp: "2024-01-02T00:00:00.000002Z"}
+	tests := []struct {
+		name   string
+		err    error
+		exists bool
+	}{
+		{"condition failed", &types.ConditionalCheckFailedException{}, true},
+		{
+			"transaction canceled by the condition",
+			&types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			}},
+			true,
+		},
+		{
+			"transaction canceled by a conflict",
+			&types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("TransactionConflict")},
+			}},
+			false,
+		},
+		{"other error", errors.New("boom"), false},
+		{"no error", nil, false},
+	}
+	for _, tt := range tests {
+		err := existsError(pk, tt.err)
+		if got := errors.Is(err, ErrTransactionExists); got != tt.exists {
+			t.Errorf("%s: existsError() = %v, want exists %v", tt.name, err, tt.exists)
+		}
+		if errors.Is(err, ErrDuplicate) {
+			t.Errorf("%s: expected an existing key not to be a duplicate, got %v", tt.name, err)
+		}
+		if !tt.exists && err != tt.err {
+			t.Errorf("%s: expected the error to be returned as is, got %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a field failing validation.
+type FieldError struct {
+	// Field is the path of the field, e.g. splits[0].amount.
+	Field string `json:"field"`
+	// Rule is the validation rule the field fails, e.g. required.
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned for records and requests failing validation,
+// with the fields failing it when they are known.
+type ValidationError struct {
+	Err    error
+	Fields []FieldError
+}
+
+// Error returns the error message, listing the fields failing the validation
+// of a struct.
+func (e *ValidationError) Error() string {
+	var verrs validator.ValidationErrors
+	if len(e.Fields) == 0 || !errors.As(e.Err, &verrs) {
+		return e.Err.Error()
+	}
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + " " + f.Message
+	}
+	return strings.Join(messages, ", ")
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// NotFoundError is returned when a record does not exist.
+type NotFoundError struct{ Err error }
+
+// Error returns the error message.
+func (e *NotFoundError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *NotFoundError) Unwrap() error { return e.Err }
+
+// ConflictError is returned when a request conflicts with the state of a record,
+// such as an illegal status transition or a concurrent update.
+type ConflictError struct{ Err error }
+
+// Error returns the error message.
+func (e *ConflictError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *ConflictError) Unwrap() error { return e.Err }
+
+// ThrottledError is returned when DynamoDB throttled a request beyond the retries.
+type ThrottledError struct{ Err error }
+
+// Error returns the error message.
+func (e *ThrottledError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *ThrottledError) Unwrap() error { return e.Err }
+
+// UnavailableError is returned when DynamoDB failed or could not be reached.
+type UnavailableError struct{ Err error }
+
+// Error returns the error message.
+func (e *UnavailableError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *UnavailableError) Unwrap() error { return e.Err }
+
+// notFound, conflict and invalid return errors of their kind with the message.
+func notFound(message string) error { return &NotFoundError{Err: errors.New(message)} }
+func conflict(message string) error { return &ConflictError{Err: errors.New(message)} }
+func invalid(message string) error  { return &ValidationError{Err: errors.New(message)} }
+
+// invalidField returns a validation error of the field wrapping err.
+func invalidField(field string, err error) error {
+	return &ValidationError{Err: err, Fields: []FieldError{{Field: field, Message: err.Error()}}}
+}
+
+// structValidator validates structs, naming fields after their JSON names.
+var structValidator = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			return snakeCase(f.Name)
+		}
+		return name
+	})
+	return v
+}
+
+// validateStruct validates a struct with its validate tags, returning a
+// ValidationError with the fields failing validation.
+func validateStruct(s interface{}) error {
+	return validationError(structValidator.Struct(s))
+}
+
+// validateStructExcept validates a struct like validateStruct, skipping the given fields.
+func validateStructExcept(s interface{}, fields ...string) error {
+	return validationError(structValidator.StructExcept(s, fields...))
+}
+
+// validationError converts the errors of the struct validator to a ValidationError.
+func validationError(err error) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	fields := make([]FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		field := fe.Namespace()
+		// the namespace starts with the name of the struct type
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		fields = append(fields, FieldError{Field: field, Rule: fe.Tag(), Message: fieldMessage(fe)})
+	}
+	return &ValidationError{Err: verrs, Fields: fields}
+}
+
+// fieldMessage returns a readable message of a field failing validation.
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without_all":
+		return fmt.Sprintf("is required without %s", fieldNames(fe.Param()))
+	case "excluded_with":
+		return fmt.Sprintf("cannot be given with %s", fieldNames(fe.Param()))
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "max":
+		return fmt.Sprintf("must be at most %s long", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "unique":
+		return "must not repeat values"
+	case "datetime":
+		return fmt.Sprintf("must be a date in the %s layout", fe.Param())
+	}
+	return fmt.Sprintf("fails the %s rule", fe.Tag())
+}
+
+// fieldNames converts the Go field names of a rule parameter to snake case.
+func fieldNames(param string) string {
+	names := strings.Fields(param)
+	for i, name := range names {
+		names[i] = snakeCase(name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// snakeCase converts a Go field name to snake case, e.g. UserID to user_id.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// classify wraps DynamoDB errors into ThrottledError and UnavailableError:
+// throttling beyond the retries, server faults and unreachable endpoints.
+func classify(err error) error {
+	var (
+		throughput *types.ProvisionedThroughputExceededException
+		limit      *types.RequestLimitExceeded
+		apiErr     smithy.APIError
+		netErr     net.Error
+	)
+	switch {
+	case errors.As(err, &throughput), errors.As(err, &limit):
+		return &ThrottledError{Err: err}
+	case errors.As(err, &apiErr):
+		if apiErr.ErrorCode() == "ThrottlingException" {
+			return &ThrottledError{Err: err}
+		}
+		if apiErr.ErrorFault() == smithy.FaultServer {
+			return &UnavailableError{Err: err}
+		}
+	case errors.As(err, &netErr), errors.Is(err, context.DeadlineExceeded):
+		return &UnavailableError{Err: err}
+	}
+	return err
+}
+
+// classifyErrors adds a middleware classifying the errors of the operations
+// of the stack, once retries are exhausted.
+func classifyErrors(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc(
+		"ClassifyErrors",
+		func(
+			ctx context.Context,
+			in middleware.InitializeInput,
+			next middleware.InitializeHandler,
+		) (middleware.InitializeOutput, middleware.Metadata, error) {
+			out, md, err := next.HandleInitialize(ctx, in)
+			if err != nil {
+				err = classify(err)
+			}
+			return out, md, err
+		},
+	), middleware.Before)
+}
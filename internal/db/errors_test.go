@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestValidateStruct(t *testing.T) {
+	tr := Transaction{
+		UserID:        "john",
+		Timestamp:     "2024-01-02T10:00:00Z",
+		ID:            "1",
+		Origin:        "web",
+		OperationType: OperationDebit,
+		Status:        "settled",
+		Splits:        []Split{{Category: "food"}},
+	}
+
+	err := validateStruct(tr)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	want := []FieldError{
+		{Field: "amount", Rule: "required", Message: "is required"},
+		{Field: "status", Rule: "oneof", Message: "must be one of pending, posted, failed, voided"},
+		{Field: "splits[0].amount", Rule: "required", Message: "is required"},
+	}
+	if !reflect.DeepEqual(verr.Fields, want) {
+		t.Errorf("unexpected fields %+v, want %+v", verr.Fields, want)
+	}
+	if got := err.Error(); got != "amount is required, status must be one of pending, posted, failed, voided, splits[0].amount is required" {
+		t.Errorf("unexpected message %q", got)
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Error("expected the validator errors to be wrapped")
+	}
+
+	if err := validateStruct(UserListRequest{UserID: "john", TimestampPrefix: "2024"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestInvalidField(t *testing.T) {
+	err := fmt.Errorf("failed to create record: %w", invalidField("category", ErrUnknownCategory))
+	if !errors.Is(err, ErrUnknownCategory) {
+		t.Error("expected ErrUnknownCategory")
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != "category" {
+		t.Errorf("unexpected validation error %+v", verr)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"UserID":          "user_id",
+		"TimestampPrefix": "timestamp_prefix",
+		"From":            "from",
+		"HTTPStatus":      "http_status",
+	} {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want interface{}
+	}{
+		{"throughput", &types.ProvisionedThroughputExceededException{}, &ThrottledError{}},
+		{"throttling", &smithy.GenericAPIError{Code: "ThrottlingException"}, &ThrottledError{}},
+		{"server fault", &smithy.GenericAPIError{Code: "InternalServerError", Fault: smithy.FaultServer}, &UnavailableError{}},
+		{"network", fmt.Errorf("dial: %w", timeoutError{}), &UnavailableError{}},
+		{"deadline", context.DeadlineExceeded, &UnavailableError{}},
+		{"client fault", &smithy.GenericAPIError{Code: "ValidationException", Fault: smithy.FaultClient}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classify(tt.err)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("expected %v to wrap %v", got, tt.err)
+			}
+			if tt.want == nil {
+				if got != tt.err {
+					t.Errorf("expected %v unchanged, got %T", tt.err, got)
+				}
+				return
+			}
+			if reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+				t.Errorf("classify(%v) = %T, want %T", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentinelKinds(t *testing.T) {
+	var (
+		notFoundErr *NotFoundError
+		conflictErr *ConflictError
+	)
+	if !errors.As(fmt.Errorf("get: %w", ErrTransactionNotFound), &notFoundErr) {
+		t.Error("expected ErrTransactionNotFound to be a NotFoundError")
+	}
+	if !errors.As(&DuplicateError{}, &conflictErr) || !errors.Is(&DuplicateError{}, ErrDuplicate) {
+		t.Error("expected DuplicateError to be a conflict")
+	}
+}
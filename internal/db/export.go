@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+// Export job statuses
+const (
+	ExportQueued    = "queued"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+const (
+	// ExportPageSize is the number of transactions an export job queries per page.
+	ExportPageSize = 1000
+	// MaxExportAttempts is how many times a failing chunk of an export job is attempted.
+	MaxExportAttempts = 3
+)
+
+// ErrExportNotFound is returned when an export job does not exist.
+var ErrExportNotFound = notFound("export not found")
+
+// ExportJob represents an asynchronous export of the transactions of a user.
+// The job is processed in chunks of query pages, each chunk checkpointing
+// the cursor of the next page, so that a job can resume where it stopped.
+type ExportJob struct {
+	ID     string `json:"export_id" dynamodbav:"export_id" validate:"required"`
+	UserID string `json:"user_id"   dynamodbav:"user_id"   validate:"required"`
+	Format string `json:"format"    dynamodbav:"format"    validate:"required"`
+	From   string `json:"from,omitempty" dynamodbav:"from,omitempty"`
+	To     string `json:"to,omitempty"   dynamodbav:"to,omitempty"`
+	Status string `json:"status"    dynamodbav:"status"    validate:"required,oneof=queued running completed failed"`
+	// Cursor is the cursor of the next page to export.
+	Cursor string `json:"-" dynamodbav:"cursor,omitempty"`
+	// Parts is the number of chunks written so far.
+	Parts int `json:"parts" dynamodbav:"parts"`
+	// Count is the number of transactions exported so far.
+	Count int `json:"count" dynamodbav:"count"`
+	// Attempts is the number of failed attempts of the current chunk.
+	Attempts int    `json:"attempts,omitempty" dynamodbav:"attempts,omitempty"`
+	Error    string `json:"error,omitempty"    dynamodbav:"error,omitempty"`
+	// File is the key of the exported file in the object store once completed.
+	File      string `json:"file,omitempty" dynamodbav:"file,omitempty"`
+	CreatedAt string `json:"created_at"     dynamodbav:"created_at"`
+	UpdatedAt string `json:"updated_at"     dynamodbav:"updated_at"`
+}
+
+// SetDefaults sets the job ID, status and creation time if not set.
+func (j *ExportJob) SetDefaults() {
+	if j.ID == "" {
+		j.ID = uuid.New().String()
+	}
+	if j.Status == "" {
+		j.Status = ExportQueued
+	}
+	if j.CreatedAt == "" {
+		j.CreatedAt = Timestamp()
+	}
+	if j.UpdatedAt == "" {
+		j.UpdatedAt = j.CreatedAt
+	}
+}
+
+// Validate validates the job and its range.
+func (j ExportJob) Validate() error {
+	if err := validateStruct(&j); err != nil {
+		return err
+	}
+	return j.ListRequest().ValidateExport()
+}
+
+// ListRequest returns the request of the next page to export.
+func (j ExportJob) ListRequest() UserListRequest {
+	limit := int32(ExportPageSize)
+	return UserListRequest{
+		UserID: j.UserID,
+		From:   j.From,
+		To:     j.To,
+		After:  j.Cursor,
+		Limit:  &limit,
+	}
+}
+
+// IsDone reports whether the job is completed or failed.
+func (j ExportJob) IsDone() bool {
+	return j.Status == ExportCompleted || j.Status == ExportFailed
+}
+
+// Fail records a failed attempt of the current chunk.
+// The job fails once the chunk ran out of attempts.
+func (j *ExportJob) Fail(err error) {
+	j.Attempts++
+	j.Error = err.Error()
+	if j.Attempts >= MaxExportAttempts {
+		j.Status = ExportFailed
+	}
+}
+
+// exportKey returns the primary key attributes of an export job.
+func exportKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"export_id": &types.AttributeValueMemberS{Value: id},
+	}
+}
+
+// CreateExportJob creates a queued export job
+func (c *Client) CreateExportJob(ctx context.Context, j *ExportJob) error {
+	j.SetDefaults()
+
+	if err := j.Validate(); err != nil {
+		return err
+	}
+
+	av, err := attributevalue.MarshalMap(j)
+	if err != nil {
+		return err
+	}
+	_, err = c.c.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(c.exports),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(export_id)"),
+	})
+	return err
+}
+
+// GetExportJob fetches an export job by its ID
+func (c *Client) GetExportJob(ctx context.Context, id string) (ExportJob, error) {
+	res, err := c.c.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.exports),
+		Key:            exportKey(id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return ExportJob{}, err
+	}
+	if res.Item == nil {
+		return ExportJob{}, ErrExportNotFound
+	}
+
+	var j ExportJob
+	if err := attributevalue.UnmarshalMap(res.Item, &j); err != nil {
+		return ExportJob{}, fmt.Errorf("failed to decode export job: %w", err)
+	}
+	return j, nil
+}
+
+// SaveExportJob checkpoints the progress of an export job. The stored job
+// must still be in progress with the given number of parts, otherwise
+// another worker got ahead and ErrConcurrentUpdate is returned.
+func (c *Client) SaveExportJob(ctx context.Context, j ExportJob, parts int) error {
+	j.UpdatedAt = Timestamp()
+
+	av, err := attributevalue.MarshalMap(j)
+	if err != nil {
+		return err
+	}
+	_, err = c.c.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(c.exports),
+		Item:                av,
+		ConditionExpression: aws.String("parts = :parts AND #status IN (:queued, :running)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":parts":   &types.AttributeValueMemberN{Value: fmt.Sprint(parts)},
+			":queued":  &types.AttributeValueMemberS{Value: ExportQueued},
+			":running": &types.AttributeValueMemberS{Value: ExportRunning},
+		},
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return ErrConcurrentUpdate
+	}
+	return err
+}
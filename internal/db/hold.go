@@ -0,0 +1,379 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+// Hold statuses
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+// DefaultHoldTTL is how long a hold reserves funds when no TTL is given.
+const DefaultHoldTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrHoldNotFound is returned when a hold does not exist.
+	ErrHoldNotFound = notFound("hold not found")
+	// ErrHoldNotActive is returned when a hold was already captured, released or expired.
+	ErrHoldNotActive = conflict("hold is not active")
+	// ErrCaptureExceedsHold is returned when the capture amount is greater than the held amount.
+	ErrCaptureExceedsHold = conflict("capture amount exceeds held amount")
+	// ErrInvalidCaptureAmount is returned for captures of negative amounts.
+	ErrInvalidCaptureAmount = invalid("capture amount must not be negative")
+	// ErrHoldExists is returned when creating a hold with the ID of an existing hold.
+	ErrHoldExists = conflict("hold already exists")
+)
+
+// Hold represents an authorization hold that reserves funds of a user.
+// An active hold reduces the available balance but not the ledger balance.
+type Hold struct {
+	UserID    string  `json:"user_id"    dynamodbav:"user_id"    validate:"required"`
+	ID        string  `json:"hold_id"    dynamodbav:"hold_id"    validate:"required"`
+	Origin    string  `json:"origin"     dynamodbav:"origin"     validate:"required"`
+	Amount    float64 `json:"amount"     dynamodbav:"amount"     validate:"required,gt=0"`
+	Status    string  `json:"status"     dynamodbav:"status"     validate:"required,oneof=active captured released expired"`
+	CreatedAt string  `json:"created_at" dynamodbav:"created_at" validate:"required"`
+	// ExpiresAt is the expiry time in unix seconds, used as the table TTL attribute.
+	// It is removed once the hold is no longer active.
+	ExpiresAt int64 `json:"expires_at,omitempty" dynamodbav:"expires_at,omitempty"`
+	// TTLSeconds is only used on creation to compute ExpiresAt.
+	TTLSeconds int64 `json:"ttl_seconds,omitempty" dynamodbav:"-" validate:"gte=0"`
+	// Captured is the amount captured from the hold.
+	Captured float64 `json:"captured,omitempty" dynamodbav:"captured,omitempty"`
+	// Transaction is the primary key of the transaction produced by the capture.
+	Transaction *TransactionPK `json:"transaction,omitempty" dynamodbav:"transaction,omitempty"`
+}
+
+// start sets the fields of a hold to create that are owned by the client,
+// ignoring the given ones: a new ID, the active status, the creation time
+// and the expiry time TTLSeconds or DefaultHoldTTL after now.
+func (h *Hold) start(now time.Time) {
+	h.ID = uuid.New().String()
+	h.Status = HoldActive
+	h.CreatedAt = now.UTC().Format(TimestampLayout)
+	ttl := DefaultHoldTTL
+	if h.TTLSeconds > 0 {
+		ttl = time.Duration(h.TTLSeconds) * time.Second
+	}
+	h.ExpiresAt = now.Add(ttl).Unix()
+	h.Captured = 0
+	h.Transaction = nil
+}
+
+// Validate validates the hold
+func (h Hold) Validate() error {
+	return validateStruct(&h)
+}
+
+// IsActive reports whether the hold still reserves funds at the given time.
+// Expired holds may linger in the table until the TTL process deletes them.
+func (h Hold) IsActive(now time.Time) bool {
+	return h.Status == HoldActive && (h.ExpiresAt == 0 || now.Unix() < h.ExpiresAt)
+}
+
+// CaptureTransaction returns the debit transaction produced by capturing amount
+// from the hold. A zero amount captures the full hold.
+func (h Hold) CaptureTransaction(amount float64, now time.Time) (Transaction, error) {
+	if err := validateCaptureAmount(amount); err != nil {
+		return Transaction{}, err
+	}
+	if !h.IsActive(now) {
+		return Transaction{}, ErrHoldNotActive
+	}
+	if amount == 0 {
+		amount = h.Amount
+	}
+	if amount > h.Amount {
+		return Transaction{}, ErrCaptureExceedsHold
+	}
+
+	return Transaction{
+		UserID:        h.UserID,
+		Origin:        h.Origin,
+		OperationType: OperationDebit,
+		Amount:        amount,
+	}, nil
+}
+
+// validateCaptureAmount validates the amount of a capture, zero capturing the full hold.
+func validateCaptureAmount(amount float64) error {
+	if amount < 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidCaptureAmount, amount)
+	}
+	return nil
+}
+
+// holdKey returns the primary key attributes of a hold.
+func holdKey(userID, holdID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"user_id": &types.AttributeValueMemberS{Value: userID},
+		"hold_id": &types.AttributeValueMemberS{Value: holdID},
+	}
+}
+
+// Balance represents the balances of a user.
+type Balance struct {
+	UserID string `json:"user_id"`
+	// Ledger is the sum of all posted credits minus debits.
+	Ledger float64 `json:"ledger"`
+	// Held is the sum of all active holds and pending debits.
+	Held float64 `json:"held"`
+	// Available is the ledger balance minus the held amount.
+	Available float64 `json:"available"`
+}
+
+// CreateHold creates an active hold
+func (c *Client) CreateHold(ctx context.Context, h *Hold) error {
+	h.start(time.Now())
+
+	if err := h.Validate(); err != nil {
+		return err
+	}
+
+	av, err := attributevalue.MarshalMap(h)
+	if err != nil {
+		return err
+	}
+	_, err = c.c.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(c.holds),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(hold_id)"),
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return fmt.Errorf("%w: %s", ErrHoldExists, h.ID)
+	}
+	return err
+}
+
+// GetHold fetches a hold by its key
+func (c *Client) GetHold(ctx context.Context, userID, holdID string) (Hold, error) {
+	res, err := c.c.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.holds),
+		Key:            holdKey(userID, holdID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Hold{}, err
+	}
+	if res.Item == nil {
+		return Hold{}, ErrHoldNotFound
+	}
+
+	var h Hold
+	if err := attributevalue.UnmarshalMap(res.Item, &h); err != nil {
+		return Hold{}, fmt.Errorf("failed to decode hold: %w", err)
+	}
+	return h, nil
+}
+
+// ListHolds lists all holds of a user
+func (c *Client) ListHolds(ctx context.Context, userID string) ([]Hold, error) {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(expression.Key("user_id").Equal(expression.Value(userID))).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to make key expression: %w", err)
+	}
+
+	holds := []Hold{}
+	paginator := dynamodb.NewQueryPaginator(c.c, &dynamodb.QueryInput{
+		TableName:                 aws.String(c.holds),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for paginator.HasMorePages() {
+		res, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var page []Hold
+		if err := attributevalue.UnmarshalListOfMaps(res.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode holds: %w", err)
+		}
+		holds = append(holds, page...)
+	}
+	return holds, nil
+}
+
+// CaptureHold captures amount from an active hold, or the full hold if amount is zero.
+// The captured amount is written as a debit transaction and the rest of the hold is released.
+func (c *Client) CaptureHold(
+	ctx context.Context,
+	userID, holdID string,
+	amount float64,
+) (Transaction, error) {
+	if err := validateCaptureAmount(amount); err != nil {
+		return Transaction{}, err
+	}
+	h, err := c.GetHold(ctx, userID, holdID)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tr, err := h.CaptureTransaction(amount, time.Now())
+	if err != nil {
+		return Transaction{}, err
+	}
+	tr.SetDefaults()
+	if err := tr.Validate(); err != nil {
+		return Transaction{}, err
+	}
+
+	trItem, err := attributevalue.MarshalMap(tr)
+	if err != nil {
+		return Transaction{}, err
+	}
+	pk, err := attributevalue.Marshal(tr.PK())
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	_, err = c.c.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName: aws.String(c.table),
+					Item:      trItem,
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName:           aws.String(c.holds),
+					Key:                 holdKey(userID, holdID),
+					UpdateExpression:    aws.String("SET #status = :captured, captured = :amount, #tr = :tr REMOVE expires_at"),
+					ConditionExpression: aws.String("#status = :active"),
+					ExpressionAttributeNames: map[string]string{
+						"#status": "status",
+						"#tr":     "transaction",
+					},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":captured": &types.AttributeValueMemberS{Value: HoldCaptured},
+						":active":   &types.AttributeValueMemberS{Value: HoldActive},
+						":amount":   &types.AttributeValueMemberN{Value: fmt.Sprint(tr.Amount)},
+						":tr":       pk,
+					},
+				},
+			},
+		},
+	})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		return Transaction{}, ErrHoldNotActive
+	}
+	if err != nil {
+		return Transaction{}, err
+	}
+	return tr, nil
+}
+
+// ReleaseHold releases an active hold without producing a transaction.
+func (c *Client) ReleaseHold(ctx context.Context, userID, holdID string) (Hold, error) {
+	h, err := c.GetHold(ctx, userID, holdID)
+	if err != nil {
+		return Hold{}, err
+	}
+	if !h.IsActive(time.Now()) {
+		return Hold{}, ErrHoldNotActive
+	}
+
+	res, err := c.c.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(c.holds),
+		Key:                 holdKey(userID, holdID),
+		UpdateExpression:    aws.String("SET #status = :released REMOVE expires_at"),
+		ConditionExpression: aws.String("#status = :active"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":released": &types.AttributeValueMemberS{Value: HoldReleased},
+			":active":   &types.AttributeValueMemberS{Value: HoldActive},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return Hold{}, ErrHoldNotActive
+	}
+	if err != nil {
+		return Hold{}, err
+	}
+
+	if err := attributevalue.UnmarshalMap(res.Attributes, &h); err != nil {
+		return Hold{}, fmt.Errorf("failed to decode hold: %w", err)
+	}
+	return h, nil
+}
+
+// ExpireHold records a hold deleted by the table TTL as expired.
+// It is called by the stream handler with the image of the deleted hold.
+func (c *Client) ExpireHold(ctx context.Context, h Hold) error {
+	if h.Status != HoldActive {
+		return nil
+	}
+	h.Status = HoldExpired
+	h.ExpiresAt = 0
+
+	av, err := attributevalue.MarshalMap(h)
+	if err != nil {
+		return err
+	}
+	_, err = c.c.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(c.holds),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(hold_id)"),
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		// the hold was already recorded, stream records may be delivered more than once
+		return nil
+	}
+	return err
+}
+
+// Balance computes the ledger and available balances of a user.
+func (c *Client) Balance(ctx context.Context, userID string) (Balance, error) {
+	b := Balance{UserID: userID}
+
+	err := c.ForEach(ctx, UserListRequest{UserID: userID}, func(t Transaction) error {
+		switch {
+		case t.Status == StatusPosted:
+			b.Ledger += t.SignedAmount()
+		case t.Status == StatusPending && t.OperationType == OperationDebit:
+			b.Held += t.Amount
+		}
+		return nil
+	})
+	if err != nil {
+		return Balance{}, err
+	}
+
+	holds, err := c.ListHolds(ctx, userID)
+	if err != nil {
+		return Balance{}, err
+	}
+	now := time.Now()
+	for _, h := range holds {
+		if h.IsActive(now) {
+			b.Held += h.Amount
+		}
+	}
+
+	b.Available = b.Ledger - b.Held
+	return b, nil
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHold_IsActive(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		hold Hold
+		want bool
+	}{
+		{"active", Hold{Status: HoldActive, ExpiresAt: now.Add(time.Hour).Unix()}, true},
+		{"active without expiry", Hold{Status: HoldActive}, true},
+		{"expired by ttl", Hold{Status: HoldActive, ExpiresAt: now.Add(-time.Hour).Unix()}, false},
+		{"captured", Hold{Status: HoldCaptured}, false},
+		{"released", Hold{Status: HoldReleased}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.hold.IsActive(now); got != test.want {
+			t.Errorf("%s: IsActive() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestHold_CaptureTransaction(t *testing.T) {
+	now := time.Now()
+	h := Hold{
+		UserID:    "john",
+		ID:        "h1",
+		Origin:    "web",
+		Amount:    100,
+		Status:    HoldActive,
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	}
+
+	tests := []struct {
+		name   string
+		hold   Hold
+		amount float64
+		want   float64
+		err    error
+	}{
+		{"full capture", h, 0, 100, nil},
+		{"partial capture", h, 40, 40, nil},
+		{"capture exceeding hold", h, 101, 0, ErrCaptureExceedsHold},
+		{"capture of released hold", Hold{Status: HoldReleased, Amount: 100}, 0, 0, ErrHoldNotActive},
+		{"negative capture", h, -1, 0, ErrInvalidCaptureAmount},
+	}
+
+	for _, test := range tests {
+		tr, err := test.hold.CaptureTransaction(test.amount, now)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if tr.Amount != test.want {
+			t.Errorf("%s: expected amount %v, got %v", test.name, test.want, tr.Amount)
+		}
+		if tr.OperationType != OperationDebit || tr.UserID != h.UserID || tr.Origin != h.Origin {
+			t.Errorf("%s: unexpected transaction %+v", test.name, tr)
+		}
+	}
+}
+
+func TestHold_Start(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	pk := TransactionPK{UserID: "john", Timestamp: "2024-01-15T10:00:00Z"}
+	h := Hold{
+		UserID:      "john",
+		ID:          "h1",
+		Origin:      "web",
+		Amount:      10,
+		Status:      HoldCaptured,
+		CreatedAt:   "2020-01-01T00:00:00Z",
+		ExpiresAt:   1,
+		TTLSeconds:  60,
+		Captured:    10,
+		Transaction: &pk,
+	}
+	h.start(now)
+
+	if err := h.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if h.ID == "h1" || h.ID == "" {
+		t.Errorf("expected a new hold ID, got %q", h.ID)
+	}
+	if h.Status != HoldActive {
+		t.Errorf("expected status %q, got %q", HoldActive, h.Status)
+	}
+	if h.CreatedAt != "2024-01-15T10:00:00Z" {
+		t.Errorf("expected creation time %q, got %q", "2024-01-15T10:00:00Z", h.CreatedAt)
+	}
+	if d := h.ExpiresAt - now.Unix(); d != 60 {
+		t.Errorf("expected hold to expire in 60s, expires in %ds", d)
+	}
+	if h.Captured != 0 || h.Transaction != nil {
+		t.Errorf("expected no capture, got %v and %v", h.Captured, h.Transaction)
+	}
+
+	h = Hold{UserID: "john", Origin: "web", Amount: 10}
+	h.start(now)
+	if d := time.Duration(h.ExpiresAt-now.Unix()) * time.Second; d != DefaultHoldTTL {
+		t.Errorf("expected hold to expire in %v, expires in %v", DefaultHoldTTL, d)
+	}
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MetadataParamPrefix is the prefix of the list query parameters filtering by metadata,
+// e.g. metadata.order_id=42.
+const MetadataParamPrefix = "metadata."
+
+// ErrInvalidMetadataKey is returned when a metadata key has characters other than
+// letters, digits, underscores and dashes.
+var ErrInvalidMetadataKey = invalid("metadata keys may only contain letters, digits, '_' and '-'")
+
+// metadataKeyRe matches valid metadata keys. Keys are restricted so that they can
+// be used in filter expression paths and query parameter names.
+var metadataKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validateMetadataKeys checks the metadata keys are valid.
+func validateMetadataKeys(metadata map[string]string) error {
+	for key := range metadata {
+		if !metadataKeyRe.MatchString(key) {
+			return invalidField("metadata", fmt.Errorf("%w: %q", ErrInvalidMetadataKey, key))
+		}
+	}
+	return nil
+}
+
+// metadataFromQuery returns the metadata filters of the query parameters.
+func metadataFromQuery(params map[string]string) map[string]string {
+	var metadata map[string]string
+	for name, value := range params {
+		if !strings.HasPrefix(name, MetadataParamPrefix) {
+			continue
+		}
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadata[strings.TrimPrefix(name, MetadataParamPrefix)] = value
+	}
+	return metadata
+}
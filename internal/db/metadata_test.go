@@ -0,0 +1,128 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+)
+
+func newDescribedTransaction() Transaction {
+	return Transaction{
+		UserID:        "john",
+		Timestamp:     "2024-01-15T10:00:00.822373Z",
+		ID:            "t1",
+		Origin:        "web",
+		OperationType: OperationDebit,
+		Amount:        42,
+		Status:        StatusPosted,
+		Description:   "Order #1234, 2 items",
+		Counterparty:  "ACME Corp.",
+		Metadata: map[string]string{
+			"order_id":   "1234",
+			"invoice-no": "INV-2024-001",
+		},
+	}
+}
+
+func TestTransaction_MetadataRoundTrip(t *testing.T) {
+	tr := newDescribedTransaction()
+
+	b, err := json.Marshal(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Transaction
+	if err := json.Unmarshal(b, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON, tr) {
+		t.Errorf("expected %+v after JSON round trip, got %+v", tr, fromJSON)
+	}
+
+	av, err := attributevalue.MarshalMap(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromDynamoDB Transaction
+	if err := attributevalue.UnmarshalMap(av, &fromDynamoDB); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromDynamoDB, tr) {
+		t.Errorf("expected %+v after DynamoDB round trip, got %+v", tr, fromDynamoDB)
+	}
+}
+
+func TestTransaction_ValidateMetadata(t *testing.T) {
+	tooMany := map[string]string{}
+	for _, key := range strings.Split("abcdefghijklmnopqrstu", "") {
+		tooMany[key] = key
+	}
+
+	tests := []struct {
+		name   string
+		modify func(tr *Transaction)
+		valid  bool
+	}{
+		{"valid", func(tr *Transaction) {}, true},
+		{"long description", func(tr *Transaction) { tr.Description = strings.Repeat("a", 513) }, false},
+		{"long counterparty", func(tr *Transaction) { tr.Counterparty = strings.Repeat("a", 129) }, false},
+		{"too many metadata keys", func(tr *Transaction) { tr.Metadata = tooMany }, false},
+		{"long metadata key", func(tr *Transaction) { tr.Metadata = map[string]string{strings.Repeat("k", 65): "v"} }, false},
+		{"long metadata value", func(tr *Transaction) { tr.Metadata = map[string]string{"k": strings.Repeat("v", 257)} }, false},
+	}
+
+	for _, test := range tests {
+		tr := newDescribedTransaction()
+		test.modify(&tr)
+		if err := tr.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v, got error %v", test.name, test.valid, err)
+		}
+	}
+
+	tr := newDescribedTransaction()
+	tr.Metadata = map[string]string{"order.id": "1"}
+	if err := tr.Validate(); !errors.Is(err, ErrInvalidMetadataKey) {
+		t.Errorf("expected %v, got %v", ErrInvalidMetadataKey, err)
+	}
+}
+
+func TestUserListRequest_MetadataFilters(t *testing.T) {
+	params := map[string]string{
+		"origin":              "web",
+		"description":         "Order",
+		"metadata.order_id":   "1234",
+		"metadata.invoice-no": "INV-2024-001",
+	}
+	want := map[string]string{"order_id": "1234", "invoice-no": "INV-2024-001"}
+	if got := metadataFromQuery(params); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	req := UserListRequest{
+		UserID:          "john",
+		TimestampPrefix: "2024",
+		Description:     "Order",
+		Counterparty:    "ACME Corp.",
+		Metadata:        want,
+	}
+	expr, err := req.ToExpression()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(*expr.Filter(), "contains (") {
+		t.Errorf("expected description filter, got %s", *expr.Filter())
+	}
+
+	tr := newDescribedTransaction()
+	if !req.Matches(tr) {
+		t.Errorf("expected %+v to match %+v", tr, req)
+	}
+	tr.Metadata["order_id"] = "4321"
+	if req.Matches(tr) {
+		t.Errorf("expected %+v not to match %+v", tr, req)
+	}
+}
@@ -2,14 +2,21 @@ package db
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/go-playground/validator/v10"
 )
 
+// timestampUpperBound follows all characters of a timestamp, so that
+// a timestamp prefix followed by it bounds all timestamps with the prefix.
+const timestampUpperBound = "~"
+
+// ErrInvalidRange is returned when the from bound of a request follows its to bound.
+var ErrInvalidRange = invalid("invalid timestamp range")
+
 // ListResponse represents a list of transactions with an optional cursor for the next page.
 type ListResponse struct {
 	Items  []Transaction `json:"items"`
@@ -18,12 +25,20 @@ type ListResponse struct {
 
 // TransactionListRequest represents a query to list transactions.
 type UserListRequest struct {
-	UserID          string `validate:"required"` // partition key
-	TimestampPrefix string `validate:"required"` // sort key to use as a prefix. Examples: "2020-01", "2020-01-01"
-	Origin          string // filter by origin
-	OperationType   string // filter by operation type
-	After           string // cursor for the next page
-	Limit           *int32 // max number of items to return
+	UserID          string            `validate:"required"`                                           // partition key
+	TimestampPrefix string            `validate:"required_without_all=From To,excluded_with=From To"` // sort key to use as a prefix. Examples: "2020-01", "2020-01-01"
+	From            string            // inclusive lower bound of the sort key, instead of the prefix. Example: "2020-01-01"
+	To              string            // inclusive upper bound of the sort key, as a prefix. Example: "2020-12" includes all of December
+	Origin          string            // filter by origin
+	OperationType   string            // filter by operation type
+	Status          string            `validate:"omitempty,oneof=pending posted failed voided"` // filter by status
+	Category        string            `validate:"excluded_with=Tag"`                            // list by category using the category index
+	Tag             string            // list by tag using the tags table
+	Description     string            `validate:"max=512"` // filter by a part of the description
+	Counterparty    string            `validate:"max=128"` // filter by counterparty
+	Metadata        map[string]string `validate:"max=20"`  // filter by metadata values
+	After           string            // cursor for the next page
+	Limit           *int32            // max number of items to return
 }
 
 // UserListRequestFromAPIGatewayProxyRequest converts an API Gateway proxy request to a UserListRequest.
@@ -37,8 +52,16 @@ func UserListRequestFromAPIGatewayProxyRequest(
 	return UserListRequest{
 		UserID:          req.PathParameters["user_id"],
 		TimestampPrefix: req.PathParameters["ts"],
+		From:            req.QueryStringParameters["from"],
+		To:              req.QueryStringParameters["to"],
 		Origin:          req.QueryStringParameters["origin"],
 		OperationType:   req.QueryStringParameters["operation_type"],
+		Status:          req.QueryStringParameters["status"],
+		Category:        req.QueryStringParameters["category"],
+		Tag:             req.QueryStringParameters["tag"],
+		Description:     req.QueryStringParameters["description"],
+		Counterparty:    req.QueryStringParameters["counterparty"],
+		Metadata:        metadataFromQuery(req.QueryStringParameters),
 		After:           req.QueryStringParameters["after"],
 		Limit:           limit,
 	}, nil
@@ -46,26 +69,103 @@ func UserListRequestFromAPIGatewayProxyRequest(
 
 // Validate validates the request.
 func (req UserListRequest) Validate() error {
-	return validator.New().Struct(req)
+	return req.validate(validateStruct(req))
+}
+
+// ValidateExport validates the request of an export. Unlike a list request,
+// an export needs no timestamp prefix or range: without one, all the
+// transactions of the user are exported.
+func (req UserListRequest) ValidateExport() error {
+	if req.TimestampPrefix != "" {
+		return req.Validate()
+	}
+	return req.validate(validateStructExcept(req, "TimestampPrefix"))
+}
+
+// validate checks the range and metadata of the request once its fields are validated.
+func (req UserListRequest) validate(err error) error {
+	if err != nil {
+		return err
+	}
+	if req.From != "" && req.To != "" && req.From > req.To {
+		return fmt.Errorf("%w: from %s follows to %s", ErrInvalidRange, req.From, req.To)
+	}
+	return validateMetadataKeys(req.Metadata)
+}
+
+// withTimestampKey adds the sort key condition of the request to the key condition:
+// either the timestamp prefix or the from and to bounds.
+func (req UserListRequest) withTimestampKey(
+	keyCond expression.KeyConditionBuilder,
+) expression.KeyConditionBuilder {
+	ts := expression.Key("ts")
+	switch {
+	case req.TimestampPrefix != "":
+		return keyCond.And(ts.BeginsWith(req.TimestampPrefix))
+	case req.From != "" && req.To != "":
+		return keyCond.And(ts.Between(
+			expression.Value(req.From),
+			expression.Value(req.To+timestampUpperBound),
+		))
+	case req.From != "":
+		return keyCond.And(ts.GreaterThanEqual(expression.Value(req.From)))
+	case req.To != "":
+		return keyCond.And(ts.LessThanEqual(expression.Value(req.To + timestampUpperBound)))
+	}
+	return keyCond
 }
 
 // ToExpression converts the request to a DynamoDB expression.
+// Requests listing by category use the key of the category index.
 func (req UserListRequest) ToExpression() (expression.Expression, error) {
 	builder := expression.NewBuilder()
-	keyCond := expression.Key("user_id").
-		Equal(expression.Value(req.UserID)).
-		And(expression.Key("ts").BeginsWith(req.TimestampPrefix))
-	builder = builder.WithKeyCondition(keyCond)
+	keyCond := expression.Key("user_id").Equal(expression.Value(req.UserID))
+	if req.Category != "" {
+		keyCond = expression.Key("user_category").Equal(
+			expression.Value(indexKey(req.UserID, req.Category)),
+		)
+	}
+	builder = builder.WithKeyCondition(req.withTimestampKey(keyCond))
 
+	var filters []expression.ConditionBuilder
 	if req.Origin != "" {
-		builder = builder.WithFilter(expression.Name("origin").Equal(expression.Value(req.Origin)))
+		filters = append(filters, expression.Name("origin").Equal(expression.Value(req.Origin)))
 	}
 
 	if req.OperationType != "" {
-		builder = builder.WithFilter(
+		filters = append(filters,
 			expression.Name("operation_type").Equal(expression.Value(req.OperationType)),
 		)
 	}
+
+	if req.Status != "" {
+		status := expression.Name("status").Equal(expression.Value(req.Status))
+		if req.Status == StatusPosted {
+			// transactions created before statuses were introduced are posted
+			status = status.Or(expression.AttributeNotExists(expression.Name("status")))
+		}
+		filters = append(filters, status)
+	}
+
+	if req.Description != "" {
+		filters = append(filters, expression.Name("description").Contains(req.Description))
+	}
+
+	if req.Counterparty != "" {
+		filters = append(filters,
+			expression.Name("counterparty").Equal(expression.Value(req.Counterparty)),
+		)
+	}
+
+	for _, key := range sortedKeys(req.Metadata) {
+		filters = append(filters,
+			expression.Name("metadata."+key).Equal(expression.Value(req.Metadata[key])),
+		)
+	}
+
+	if filter, ok := andAll(filters); ok {
+		builder = builder.WithFilter(filter)
+	}
 	return builder.Build()
 }
 
@@ -82,8 +182,14 @@ func (req UserListRequest) ToQueryInput(tableName string) (*dynamodb.QueryInput,
 		return nil, fmt.Errorf("failed to decode last evaluated key: %w", err)
 	}
 
+	var index *string
+	if req.Category != "" {
+		index = aws.String(CategoryIndex)
+	}
+
 	return &dynamodb.QueryInput{
 		TableName:                 aws.String(tableName),
+		IndexName:                 index,
 		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
@@ -92,3 +198,28 @@ func (req UserListRequest) ToQueryInput(tableName string) (*dynamodb.QueryInput,
 		ExclusiveStartKey:         after.ToAttributes(),
 	}, nil
 }
+
+// Matches reports whether the transaction matches the filters of the request.
+// It mirrors the filter expression for results that are not filtered by DynamoDB.
+func (req UserListRequest) Matches(tr Transaction) bool {
+	switch {
+	case req.Origin != "" && tr.Origin != req.Origin:
+		return false
+	case req.OperationType != "" && tr.OperationType != req.OperationType:
+		return false
+	case req.Status != "" && tr.Status != req.Status:
+		return false
+	case req.Category != "" && tr.Category != req.Category:
+		return false
+	case req.Description != "" && !strings.Contains(tr.Description, req.Description):
+		return false
+	case req.Counterparty != "" && tr.Counterparty != req.Counterparty:
+		return false
+	}
+	for key, value := range req.Metadata {
+		if v, ok := tr.Metadata[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
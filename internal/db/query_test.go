@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 func TestUserListRequest_ToExpression(t *testing.T) {
@@ -20,9 +21,10 @@ func TestUserListRequest_ToExpression(t *testing.T) {
 
 	builder := expression.NewBuilder()
 	builder = builder.WithKeyCondition(expectedKeyCond)
-	builder = builder.WithFilter(expression.Name("origin").Equal(expression.Value(req.Origin)))
 	builder = builder.WithFilter(
-		expression.Name("operation_type").Equal(expression.Value(req.OperationType)))
+		expression.Name("origin").Equal(expression.Value(req.Origin)).
+			And(expression.Name("operation_type").Equal(expression.Value(req.OperationType))),
+	)
 
 	expectedExpr, err := builder.Build()
 	if err != nil {
@@ -45,3 +47,177 @@ func TestUserListRequest_ToExpression(t *testing.T) {
 		t.Errorf("expected filter: %s, got: %s", *expectedExpr.Filter(), *filter)
 	}
 }
+
+func TestUserListRequest_ToExpressionStatus(t *testing.T) {
+	req := UserListRequest{
+		UserID:          "123",
+		TimestampPrefix: "2021",
+		Status:          StatusPosted,
+	}
+
+	expr, err := req.ToExpression()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "(#0 = :0) OR (attribute_not_exists (#0))"
+	if *expr.Filter() != want {
+		t.Errorf("expected filter: %s, got: %s", want, *expr.Filter())
+	}
+	if expr.Names()["#0"] != "status" {
+		t.Errorf("expected #0 to be status, got: %v", expr.Names())
+	}
+}
+
+func TestUserListRequest_ToQueryInputCategory(t *testing.T) {
+	req := UserListRequest{
+		UserID:          "john",
+		TimestampPrefix: "2024-03",
+		Category:        "groceries",
+	}
+
+	input, err := req.ToQueryInput("Transactions")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if input.IndexName == nil || *input.IndexName != CategoryIndex {
+		t.Errorf("expected index %s, got %v", CategoryIndex, input.IndexName)
+	}
+	if input.ExpressionAttributeNames["#0"] != "user_category" {
+		t.Errorf("expected key condition on user_category, got %v", input.ExpressionAttributeNames)
+	}
+	if v, ok := input.ExpressionAttributeValues[":0"].(*types.AttributeValueMemberS); !ok || v.Value != "john#groceries" {
+		t.Errorf("expected partition key john#groceries, got %v", input.ExpressionAttributeValues[":0"])
+	}
+}
+
+func TestUserListRequest_ToQueryInputRange(t *testing.T) {
+	req := UserListRequest{UserID: "john", From: "2023-01-01", To: "2023-12"}
+
+	input, err := req.ToQueryInput("Transactions")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := *input.KeyConditionExpression; got != "(#0 = :0) AND (#1 BETWEEN :1 AND :2)" {
+		t.Errorf("unexpected key condition %s", got)
+	}
+	for key, want := range map[string]string{":1": "2023-01-01", ":2": "2023-12~"} {
+		if v, ok := input.ExpressionAttributeValues[key].(*types.AttributeValueMemberS); !ok || v.Value != want {
+			t.Errorf("expected %s to be %s, got %v", key, want, input.ExpressionAttributeValues[key])
+		}
+	}
+}
+
+func TestUserListRequest_ValidateRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     UserListRequest
+		wantErr bool
+	}{
+		{"prefix", UserListRequest{UserID: "john", TimestampPrefix: "2023"}, false},
+		{"from", UserListRequest{UserID: "john", From: "2023"}, false},
+		{"to", UserListRequest{UserID: "john", To: "2023"}, false},
+		{"from and to", UserListRequest{UserID: "john", From: "2023-01", To: "2023-01"}, false},
+		{"no range", UserListRequest{UserID: "john"}, true},
+		{"prefix and from", UserListRequest{UserID: "john", TimestampPrefix: "2023", From: "2023"}, true},
+		{"from after to", UserListRequest{UserID: "john", From: "2024", To: "2023"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserListRequest_ValidateExport(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     UserListRequest
+		wantErr bool
+	}{
+		{"no range", UserListRequest{UserID: "john"}, false},
+		{"from", UserListRequest{UserID: "john", From: "2023"}, false},
+		{"prefix", UserListRequest{UserID: "john", TimestampPrefix: "2023"}, false},
+		{"no user", UserListRequest{}, true},
+		{"prefix and from", UserListRequest{UserID: "john", TimestampPrefix: "2023", From: "2023"}, true},
+		{"from after to", UserListRequest{UserID: "john", From: "2024", To: "2023"}, true},
+		{"invalid status", UserListRequest{UserID: "john", Status: "unknown"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.ValidateExport(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateExport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	job := ExportJob{ID: "a", UserID: "john", Format: "csv", Status: ExportQueued}
+	if err := job.Validate(); err != nil {
+		t.Errorf("expected an export job without range to be valid, got %v", err)
+	}
+}
+
+func TestUserListRequest_Validate(t *testing.T) {
+	req := UserListRequest{UserID: "john", TimestampPrefix: "2024", Category: "groceries"}
+	if err := req.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	req.Tag = "vacation"
+	if err := req.Validate(); err == nil {
+		t.Errorf("expected error listing by both category and tag")
+	}
+}
+
+func TestUserListRequest_Matches(t *testing.T) {
+	tr := Transaction{Origin: "web", OperationType: OperationDebit, Status: StatusPosted, Category: "groceries"}
+
+	tests := []struct {
+		req  UserListRequest
+		want bool
+	}{
+		{UserListRequest{}, true},
+		{UserListRequest{Origin: "web", Status: StatusPosted}, true},
+		{UserListRequest{Origin: "ios"}, false},
+		{UserListRequest{OperationType: OperationCredit}, false},
+		{UserListRequest{Status: StatusPending}, false},
+		{UserListRequest{Category: "groceries"}, true},
+		{UserListRequest{Category: "travel"}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.req.Matches(tr); got != test.want {
+			t.Errorf("%+v: Matches() = %v, want %v", test.req, got, test.want)
+		}
+	}
+}
+
+func TestTransactionPK_Cursor(t *testing.T) {
+	attrs := map[string]types.AttributeValue{
+		"user_tag": &types.AttributeValueMemberS{Value: "john#vacation"},
+		"ts":       &types.AttributeValueMemberS{Value: "2024-03-01T10:00:00Z"},
+	}
+
+	cursor, err := TransactionPKFromAttributes(attrs).ToBase64()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pk, err := TransactionPKFromBase64(cursor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := pk.ToAttributes()
+	if len(got) != len(attrs) {
+		t.Fatalf("expected %v, got %v", attrs, got)
+	}
+	for name, av := range attrs {
+		if stringAttr(got, name) != av.(*types.AttributeValueMemberS).Value {
+			t.Errorf("expected %s to be %v, got %v", name, av, got[name])
+		}
+	}
+}
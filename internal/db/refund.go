@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// amountEpsilon absorbs floating point errors when comparing summed amounts.
+const amountEpsilon = 1e-9
+
+var (
+	// ErrNotRefundable is returned when refunding a transaction that is not a posted debit.
+	ErrNotRefundable = conflict("only posted debits can be refunded")
+	// ErrRefundExceedsOriginal is returned when the cumulative refunded amount
+	// would exceed the amount of the original transaction.
+	ErrRefundExceedsOriginal = conflict("refunded amount exceeds original amount")
+	// ErrInvalidRefundAmount is returned for refunds of zero or negative amounts.
+	ErrInvalidRefundAmount = invalid("refund amount must be positive")
+	// ErrCreateRefund is returned when creating a refund other than by refunding
+	// its original transaction.
+	ErrCreateRefund = invalid("refunds are created by refunding the original transaction")
+	// ErrConcurrentUpdate is returned when a record was changed while being updated.
+	ErrConcurrentUpdate = conflict("record was updated concurrently")
+)
+
+// TransactionDetails is a transaction together with its refunds.
+type TransactionDetails struct {
+	Transaction
+	Refunds []Transaction `json:"refunds,omitempty"`
+}
+
+// RefundTransaction returns the refund of amount from the original transaction.
+// It checks that the original is a posted debit and that the cumulative refunded
+// amount does not exceed the original amount.
+func (tr Transaction) RefundTransaction(amount float64, origin string) (Transaction, error) {
+	if err := validateRefundAmount(amount); err != nil {
+		return Transaction{}, err
+	}
+	if tr.OperationType != OperationDebit || tr.Status != StatusPosted {
+		return Transaction{}, ErrNotRefundable
+	}
+	if tr.RefundedAmount+amount > tr.Amount+amountEpsilon {
+		return Transaction{}, fmt.Errorf(
+			"%w: %v already refunded out of %v",
+			ErrRefundExceedsOriginal,
+			tr.RefundedAmount,
+			tr.Amount,
+		)
+	}
+	if origin == "" {
+		origin = tr.Origin
+	}
+
+	pk := tr.PK()
+	return Transaction{
+		UserID:        tr.UserID,
+		Origin:        origin,
+		OperationType: OperationRefund,
+		Amount:        amount,
+		RefundOf:      &pk,
+	}, nil
+}
+
+// Refund refunds amount of the original transaction. The refund is written
+// together with the updated refunded amount of the original.
+func (c *Client) Refund(
+	ctx context.Context,
+	original TransactionPK,
+	amount float64,
+	origin string,
+) (Transaction, error) {
+	if err := validateRefundAmount(amount); err != nil {
+		return Transaction{}, err
+	}
+	tr, err := c.Get(ctx, original)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	refund, err := tr.RefundTransaction(amount, origin)
+	if err != nil {
+		return Transaction{}, err
+	}
+	refund.SetDefaults()
+	if err := refund.Validate(); err != nil {
+		return Transaction{}, err
+	}
+
+	refundItem, err := attributevalue.MarshalMap(refund)
+	if err != nil {
+		return Transaction{}, err
+	}
+	refundKey, err := attributevalue.Marshal([]TransactionPK{refund.PK()})
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	values := map[string]types.AttributeValue{
+		":amount": &types.AttributeValueMemberN{Value: fmt.Sprint(tr.RefundedAmount + amount)},
+		":refund": refundKey,
+		":empty":  &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+	}
+	// the original must not have been refunded since it was read
+	cond := "attribute_not_exists(refunded_amount)"
+	if tr.RefundedAmount != 0 {
+		cond = "refunded_amount = :prev"
+		values[":prev"] = &types.AttributeValueMemberN{Value: fmt.Sprint(tr.RefundedAmount)}
+	}
+
+	_, err = c.c.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           aws.String(c.table),
+					Item:                refundItem,
+					ConditionExpression: aws.String("attribute_not_exists(ts)"),
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName:                 aws.String(c.table),
+					Key:                       original.ToAttributes(),
+					UpdateExpression:          aws.String("SET refunded_amount = :amount, refund_keys = list_append(if_not_exists(refund_keys, :empty), :refund)"),
+					ConditionExpression:       aws.String(cond),
+					ExpressionAttributeValues: values,
+				},
+			},
+		},
+	})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		return Transaction{}, ErrConcurrentUpdate
+	}
+	if err != nil {
+		return Transaction{}, err
+	}
+	return refund, nil
+}
+
+// validateRefundAmount validates the amount of a refund, which must be positive.
+func validateRefundAmount(amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidRefundAmount, amount)
+	}
+	return nil
+}
+
+// GetDetails fetches a transaction by its primary key together with its refunds.
+func (c *Client) GetDetails(ctx context.Context, pk TransactionPK) (TransactionDetails, error) {
+	tr, err := c.Get(ctx, pk)
+	if err != nil {
+		return TransactionDetails{}, err
+	}
+
+	refunds, err := c.BatchGet(ctx, tr.RefundKeys)
+	if err != nil {
+		return TransactionDetails{}, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	return TransactionDetails{Transaction: tr, Refunds: refunds}, nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransaction_RefundTransaction(t *testing.T) {
+	original := Transaction{
+		UserID:        "john",
+		Timestamp:     "2024-01-15T10:00:00.822373Z",
+		ID:            "t1",
+		Origin:        "web",
+		OperationType: OperationDebit,
+		Amount:        100,
+		Status:        StatusPosted,
+	}
+
+	partly := original
+	partly.RefundedAmount = 70
+
+	credit := original
+	credit.OperationType = OperationCredit
+
+	pending := original
+	pending.Status = StatusPending
+
+	tests := []struct {
+		name     string
+		original Transaction
+		amount   float64
+		err      error
+	}{
+		{"full refund", original, 100, nil},
+		{"partial refund", original, 30, nil},
+		{"remaining refund", partly, 30, nil},
+		{"refund exceeding remaining amount", partly, 30.01, ErrRefundExceedsOriginal},
+		{"refund of credit", credit, 10, ErrNotRefundable},
+		{"refund of pending debit", pending, 10, ErrNotRefundable},
+		{"zero refund", original, 0, ErrInvalidRefundAmount},
+		{"negative refund", original, -10, ErrInvalidRefundAmount},
+	}
+
+	for _, test := range tests {
+		refund, err := test.original.RefundTransaction(test.amount, "")
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if refund.OperationType != OperationRefund || refund.Amount != test.amount {
+			t.Errorf("%s: unexpected refund %+v", test.name, refund)
+		}
+		if refund.RefundOf == nil || *refund.RefundOf != original.PK() {
+			t.Errorf("%s: expected refund of %v, got %v", test.name, original.PK(), refund.RefundOf)
+		}
+		if refund.Origin != original.Origin {
+			t.Errorf("%s: expected origin %q, got %q", test.name, original.Origin, refund.Origin)
+		}
+	}
+}
+
+func TestTransaction_ValidateNew(t *testing.T) {
+	pk := TransactionPK{UserID: "john", Timestamp: "2024-01-15T10:00:00.822373Z"}
+	tr := Transaction{
+		UserID:         "john",
+		Origin:         "web",
+		OperationType:  OperationDebit,
+		Amount:         100,
+		StatusHistory:  []StatusChange{{From: StatusPending, To: StatusPosted}},
+		RefundOf:       &pk,
+		RefundedAmount: 100,
+		RefundKeys:     []TransactionPK{pk},
+		DuplicateOf:    &pk,
+	}
+	if err := tr.validateNew(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if tr.StatusHistory != nil || tr.RefundOf != nil || tr.RefundedAmount != 0 ||
+		tr.RefundKeys != nil || tr.DuplicateOf != nil {
+		t.Errorf("expected the fields maintained by the client to be cleared, got %+v", tr)
+	}
+
+	refund := tr
+	refund.OperationType = OperationRefund
+	if err := refund.validateNew(); !errors.Is(err, ErrCreateRefund) {
+		t.Errorf("expected error %v, got %v", ErrCreateRefund, err)
+	}
+
+	unknown := tr
+	unknown.OperationType = "transfer"
+	var verr *ValidationError
+	if err := unknown.validateNew(); !errors.As(err, &verr) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
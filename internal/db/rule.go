@@ -0,0 +1,325 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+// maxTags is the maximum number of tags of a transaction.
+const maxTags = 10
+
+var (
+	// ErrRuleNotFound is returned when a rule does not exist.
+	ErrRuleNotFound = notFound("rule not found")
+	// ErrInvalidRule is returned when a rule matches everything or assigns nothing.
+	ErrInvalidRule = invalid("rule must have a condition and assign a category or tags")
+)
+
+// RuleMatch are the conditions of a rule, all given conditions must match.
+type RuleMatch struct {
+	// Origin must equal the transaction origin.
+	Origin string `json:"origin,omitempty" dynamodbav:"origin,omitempty"`
+	// Description must be contained in the transaction description, ignoring case.
+	Description string `json:"description,omitempty" dynamodbav:"description,omitempty"`
+	// Counterparty must be contained in the transaction counterparty, ignoring case.
+	Counterparty string `json:"counterparty,omitempty" dynamodbav:"counterparty,omitempty"`
+	// MinAmount and MaxAmount bound the transaction amount, inclusive.
+	MinAmount *float64 `json:"min_amount,omitempty" dynamodbav:"min_amount,omitempty" validate:"omitempty,gte=0"`
+	MaxAmount *float64 `json:"max_amount,omitempty" dynamodbav:"max_amount,omitempty" validate:"omitempty,gte=0"`
+}
+
+// IsEmpty reports whether the match has no conditions.
+func (m RuleMatch) IsEmpty() bool {
+	return m == RuleMatch{}
+}
+
+// Matches reports whether the transaction matches all conditions.
+func (m RuleMatch) Matches(tr Transaction) bool {
+	switch {
+	case m.Origin != "" && m.Origin != tr.Origin:
+		return false
+	case m.Description != "" && !containsFold(tr.Description, m.Description):
+		return false
+	case m.Counterparty != "" && !containsFold(tr.Counterparty, m.Counterparty):
+		return false
+	case m.MinAmount != nil && tr.Amount < *m.MinAmount:
+		return false
+	case m.MaxAmount != nil && tr.Amount > *m.MaxAmount:
+		return false
+	}
+	return true
+}
+
+// Rule assigns a category and tags to the transactions of a user matching its conditions.
+type Rule struct {
+	UserID string `json:"user_id" dynamodbav:"user_id" validate:"required"`
+	ID     string `json:"rule_id" dynamodbav:"rule_id" validate:"required"`
+	// Priority orders the rules, lower priorities are applied first.
+	Priority int       `json:"priority"           dynamodbav:"priority"`
+	Match    RuleMatch `json:"match"              dynamodbav:"match"`
+	Category string    `json:"category,omitempty" dynamodbav:"category,omitempty" validate:"max=64"`
+	Tags     []string  `json:"tags,omitempty"     dynamodbav:"tags,omitempty"     validate:"omitempty,max=10,unique,dive,required,max=64"`
+}
+
+// SetDefaults sets the rule ID if not set.
+func (r *Rule) SetDefaults() {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+}
+
+// Validate validates the rule
+func (r Rule) Validate() error {
+	if err := validateStruct(&r); err != nil {
+		return err
+	}
+	if r.Match.IsEmpty() || (r.Category == "" && len(r.Tags) == 0) {
+		return ErrInvalidRule
+	}
+	return nil
+}
+
+// Rules are the rules of a user ordered by priority.
+type Rules []Rule
+
+// NewRules orders the rules by priority, keeping the order of rules with equal priorities.
+func NewRules(rules []Rule) Rules {
+	sorted := make(Rules, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	return sorted
+}
+
+// Apply assigns the category and tags of the matching rules to the transaction.
+// The category of the first matching rule with a category is assigned, unless the
+// transaction has a category and overwrite is false. Tags of all matching rules
+// are added to the tags of the transaction. It reports whether the transaction changed.
+func (rules Rules) Apply(tr *Transaction, overwrite bool) bool {
+	changed := false
+	categorized := tr.Category != "" && !overwrite
+
+	for _, r := range rules {
+		if !r.Match.Matches(*tr) {
+			continue
+		}
+		if !categorized && r.Category != "" {
+			categorized = true
+			if tr.Category != r.Category {
+				tr.Category = r.Category
+				changed = true
+			}
+		}
+		for _, tag := range r.Tags {
+			if len(tr.Tags) < maxTags && !containsString(tr.Tags, tag) {
+				tr.Tags = append(tr.Tags, tag)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// containsFold reports whether substr is within s, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// containsString reports whether the list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleKey returns the primary key attributes of a rule.
+func ruleKey(userID, ruleID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"user_id": &types.AttributeValueMemberS{Value: userID},
+		"rule_id": &types.AttributeValueMemberS{Value: ruleID},
+	}
+}
+
+// PutRule creates or replaces a rule
+func (c *Client) PutRule(ctx context.Context, r *Rule) error {
+	r.SetDefaults()
+
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if err := c.categories.Validate(Transaction{Category: r.Category}); err != nil {
+		return err
+	}
+
+	av, err := attributevalue.MarshalMap(r)
+	if err != nil {
+		return err
+	}
+	_, err = c.c.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.rules),
+		Item:      av,
+	})
+	return err
+}
+
+// DeleteRule deletes a rule
+func (c *Client) DeleteRule(ctx context.Context, userID, ruleID string) error {
+	_, err := c.c.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(c.rules),
+		Key:                 ruleKey(userID, ruleID),
+		ConditionExpression: aws.String("attribute_exists(rule_id)"),
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return ErrRuleNotFound
+	}
+	return err
+}
+
+// Rules lists the rules of a user ordered by priority
+func (c *Client) Rules(ctx context.Context, userID string) (Rules, error) {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(expression.Key("user_id").Equal(expression.Value(userID))).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to make key expression: %w", err)
+	}
+
+	var rules []Rule
+	paginator := dynamodb.NewQueryPaginator(c.c, &dynamodb.QueryInput{
+		TableName:                 aws.String(c.rules),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for paginator.HasMorePages() {
+		res, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var page []Rule
+		if err := attributevalue.UnmarshalListOfMaps(res.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode rules: %w", err)
+		}
+		rules = append(rules, page...)
+	}
+	return NewRules(rules), nil
+}
+
+// ApplyRulesReport reports the outcome of re-applying rules to the transactions of a user.
+type ApplyRulesReport struct {
+	Scanned int `json:"scanned"`
+	Updated int `json:"updated"`
+}
+
+// ReapplyRules applies the rules of a user to the transactions matching the request,
+// updating the category and tags of the transactions that changed.
+func (c *Client) ReapplyRules(
+	ctx context.Context,
+	req UserListRequest,
+	overwrite bool,
+) (ApplyRulesReport, error) {
+	var report ApplyRulesReport
+
+	rules, err := c.Rules(ctx, req.UserID)
+	if err != nil {
+		return report, err
+	}
+	if len(rules) == 0 {
+		return report, nil
+	}
+
+	err = c.ForEach(ctx, req, func(tr Transaction) error {
+		report.Scanned++
+
+		updated := tr
+		updated.Tags = append([]string(nil), tr.Tags...)
+		if !rules.Apply(&updated, overwrite) {
+			return nil
+		}
+		if err := c.updateCategorization(ctx, tr, updated); err != nil {
+			return fmt.Errorf("failed to update transaction %s: %w", tr.Timestamp, err)
+		}
+		report.Updated++
+		return nil
+	})
+	return report, err
+}
+
+// updateCategorization writes the category and tags of updated, a changed copy of tr,
+// keeping the tags table in sync.
+func (c *Client) updateCategorization(ctx context.Context, tr, updated Transaction) error {
+	updated.SetDefaults()
+
+	// user_category is a key of the category index and cannot be an empty string
+	var set expression.UpdateBuilder
+	if updated.Category != "" {
+		set = set.Set(expression.Name("category"), expression.Value(updated.Category)).
+			Set(expression.Name("user_category"), expression.Value(updated.UserCategory))
+	} else {
+		set = set.Remove(expression.Name("category")).Remove(expression.Name("user_category"))
+	}
+	if len(updated.Tags) > 0 {
+		set = set.Set(expression.Name("tags"), expression.Value(updated.Tags))
+	} else {
+		set = set.Remove(expression.Name("tags"))
+	}
+	expr, err := expression.NewBuilder().
+		WithUpdate(set).
+		WithCondition(expression.AttributeExists(expression.Name("ts"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("failed to make update expression: %w", err)
+	}
+
+	var added, removed []string
+	for _, tag := range updated.Tags {
+		if !containsString(tr.Tags, tag) {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range tr.Tags {
+		if !containsString(updated.Tags, tag) {
+			removed = append(removed, tag)
+		}
+	}
+
+	writes, err := c.tagWrites(Transaction{
+		UserID:    updated.UserID,
+		Timestamp: updated.Timestamp,
+		Tags:      added,
+	})
+	if err != nil {
+		return err
+	}
+	writes = append(writes, c.tagDeletes(tr, removed)...)
+	writes = append(writes, types.TransactWriteItem{
+		Update: &types.Update{
+			TableName:                 aws.String(c.table),
+			Key:                       tr.PK().ToAttributes(),
+			UpdateExpression:          expr.Update(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		},
+	})
+
+	_, err = c.c.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: writes,
+	})
+	return err
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func TestRuleMatch_Matches(t *testing.T) {
+	tr := Transaction{
+		Origin:       "ios",
+		Amount:       12.5,
+		Description:  "Flat White at Blue Bottle",
+		Counterparty: "Blue Bottle Coffee Inc.",
+	}
+
+	tests := []struct {
+		name  string
+		match RuleMatch
+		want  bool
+	}{
+		{"origin", RuleMatch{Origin: "ios"}, true},
+		{"other origin", RuleMatch{Origin: "web"}, false},
+		{"description ignoring case", RuleMatch{Description: "flat white"}, true},
+		{"other description", RuleMatch{Description: "latte"}, false},
+		{"counterparty", RuleMatch{Counterparty: "blue bottle"}, true},
+		{"amount in range", RuleMatch{MinAmount: float64Ptr(10), MaxAmount: float64Ptr(12.5)}, true},
+		{"amount below range", RuleMatch{MinAmount: float64Ptr(20)}, false},
+		{"amount above range", RuleMatch{MaxAmount: float64Ptr(10)}, false},
+		{"all conditions", RuleMatch{Origin: "ios", Counterparty: "bottle", MaxAmount: float64Ptr(20)}, true},
+		{"one condition failing", RuleMatch{Origin: "ios", Counterparty: "starbucks"}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.match.Matches(tr); got != test.want {
+			t.Errorf("%s: Matches() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRules_Apply(t *testing.T) {
+	rules := NewRules([]Rule{
+		{ID: "tags", Priority: 3, Match: RuleMatch{Description: "coffee"}, Tags: []string{"coffee", "daily"}},
+		{ID: "fallback", Priority: 2, Match: RuleMatch{Origin: "ios"}, Category: "shopping"},
+		{ID: "dining", Priority: 1, Match: RuleMatch{Description: "coffee"}, Category: "dining", Tags: []string{"daily"}},
+	})
+
+	tests := []struct {
+		name      string
+		tr        Transaction
+		overwrite bool
+		want      Transaction
+		changed   bool
+	}{
+		{
+			name:    "first matching category and all matching tags",
+			tr:      Transaction{Origin: "ios", Description: "Coffee"},
+			want:    Transaction{Origin: "ios", Description: "Coffee", Category: "dining", Tags: []string{"daily", "coffee"}},
+			changed: true,
+		},
+		{
+			name:    "given category is kept",
+			tr:      Transaction{Origin: "ios", Category: "travel", Tags: []string{"trip"}},
+			want:    Transaction{Origin: "ios", Category: "travel", Tags: []string{"trip"}},
+			changed: false,
+		},
+		{
+			name:      "given category is overwritten",
+			tr:        Transaction{Origin: "ios", Category: "travel"},
+			overwrite: true,
+			want:      Transaction{Origin: "ios", Category: "shopping"},
+			changed:   true,
+		},
+		{
+			name:    "no matching rule",
+			tr:      Transaction{Origin: "web"},
+			want:    Transaction{Origin: "web"},
+			changed: false,
+		},
+	}
+
+	for _, test := range tests {
+		tr := test.tr
+		changed := rules.Apply(&tr, test.overwrite)
+		if changed != test.changed {
+			t.Errorf("%s: expected changed %v, got %v", test.name, test.changed, changed)
+		}
+		if !reflect.DeepEqual(tr, test.want) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.want, tr)
+		}
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	valid := Rule{UserID: "john", ID: "r1", Match: RuleMatch{Origin: "ios"}, Category: "dining"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	matchAll := valid
+	matchAll.Match = RuleMatch{}
+	if err := matchAll.Validate(); err != ErrInvalidRule {
+		t.Errorf("expected %v, got %v", ErrInvalidRule, err)
+	}
+
+	noAction := valid
+	noAction.Category = ""
+	if err := noAction.Validate(); err != ErrInvalidRule {
+		t.Errorf("expected %v, got %v", ErrInvalidRule, err)
+	}
+}
@@ -0,0 +1,289 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// minTokenLength is the length of the shortest indexed token.
+	minTokenLength = 2
+	// maxTokenLength is the length tokens are truncated to.
+	maxTokenLength = 64
+	// maxTokens is the maximum number of tokens indexed per transaction.
+	maxTokens = 100
+	// DefaultSearchLimit is the number of results returned when no limit is given.
+	DefaultSearchLimit = 50
+	// batchWriteSize is the maximum number of items of a BatchWriteItem request.
+	batchWriteSize = 25
+	// batchWriteAttempts is the maximum number of BatchWriteItem requests
+	// writing a batch, the first request and the retries of unprocessed items.
+	batchWriteAttempts = 8
+	// batchWriteBackoff is the delay before the first retry of unprocessed items,
+	// doubled on every retry up to maxBatchWriteBackoff.
+	batchWriteBackoff    = 50 * time.Millisecond
+	maxBatchWriteBackoff = 5 * time.Second
+)
+
+// ErrEmptySearch is returned when a search query has no searchable terms.
+var ErrEmptySearch = invalid("search query has no terms")
+
+// Tokenize splits text into lower case tokens of letters and digits, without duplicates.
+// Tokens shorter than 2 characters are dropped and long tokens are truncated.
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := map[string]bool{}
+	tokens := []string{}
+	for _, f := range fields {
+		if r := []rune(f); len(r) > maxTokenLength {
+			f = string(r[:maxTokenLength])
+		}
+		if len([]rune(f)) < minTokenLength || seen[f] {
+			continue
+		}
+		seen[f] = true
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// SearchTokens returns the tokens indexed for a transaction, from its description and counterparty.
+func (tr Transaction) SearchTokens() []string {
+	tokens := Tokenize(tr.Description + " " + tr.Counterparty)
+	if len(tokens) > maxTokens {
+		tokens = tokens[:maxTokens]
+	}
+	return tokens
+}
+
+// SearchTerm is a term of a search query, matching a whole token or a token prefix.
+type SearchTerm struct {
+	Token  string
+	Prefix bool
+}
+
+// ParseSearchQuery parses a search query into terms. Words match whole tokens,
+// words ending with * match tokens starting with the word, e.g. "coff*".
+func ParseSearchQuery(q string) []SearchTerm {
+	var terms []SearchTerm
+	for _, word := range strings.Fields(q) {
+		prefix := strings.HasSuffix(word, "*")
+		for _, token := range Tokenize(word) {
+			terms = append(terms, SearchTerm{Token: token, Prefix: prefix})
+		}
+	}
+	return terms
+}
+
+// indexPrefix returns the prefix of the index sort keys matched by the term.
+func (t SearchTerm) indexPrefix() string {
+	if t.Prefix {
+		return t.Token
+	}
+	return t.Token + "#"
+}
+
+// searchIndexItem is an entry of the search index table, keyed by user_id and term,
+// where term is token#ts.
+type searchIndexItem struct {
+	UserID    string `dynamodbav:"user_id"`
+	Term      string `dynamodbav:"term"`
+	Timestamp string `dynamodbav:"ts"`
+}
+
+// searchIndexItems returns the index entries of the tokens of a transaction.
+func searchIndexItems(tr Transaction, tokens []string) []searchIndexItem {
+	items := make([]searchIndexItem, len(tokens))
+	for i, token := range tokens {
+		items[i] = searchIndexItem{
+			UserID:    tr.UserID,
+			Term:      token + "#" + tr.Timestamp,
+			Timestamp: tr.Timestamp,
+		}
+	}
+	return items
+}
+
+// difference returns the tokens of a missing from b.
+func difference(a, b []string) []string {
+	var diff []string
+	for _, token := range a {
+		if !containsString(b, token) {
+			diff = append(diff, token)
+		}
+	}
+	return diff
+}
+
+// IndexTransaction updates the search index for a transaction changing from before to after.
+// A nil before indexes a new transaction and a nil after removes a deleted one.
+func (c *Client) IndexTransaction(ctx context.Context, before, after *Transaction) error {
+	var beforeTokens, afterTokens []string
+	if before != nil {
+		beforeTokens = before.SearchTokens()
+	}
+	if after != nil {
+		afterTokens = after.SearchTokens()
+	}
+
+	var writes []types.WriteRequest
+	if before != nil {
+		for _, item := range searchIndexItems(*before, difference(beforeTokens, afterTokens)) {
+			writes = append(writes, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+				Key: map[string]types.AttributeValue{
+					"user_id": &types.AttributeValueMemberS{Value: item.UserID},
+					"term":    &types.AttributeValueMemberS{Value: item.Term},
+				},
+			}})
+		}
+	}
+	if after != nil {
+		for _, item := range searchIndexItems(*after, difference(afterTokens, beforeTokens)) {
+			av, err := attributevalue.MarshalMap(item)
+			if err != nil {
+				return err
+			}
+			writes = append(writes, types.WriteRequest{PutRequest: &types.PutRequest{Item: av}})
+		}
+	}
+
+	return c.batchWrite(ctx, c.search, writes)
+}
+
+// batchWrite writes the requests to a table in batches, retrying unprocessed items
+// with exponential backoff. Batches with items still unprocessed after
+// batchWriteAttempts requests fail with a ThrottledError.
+func (c *Client) batchWrite(ctx context.Context, table string, writes []types.WriteRequest) error {
+	for start := 0; start < len(writes); start += batchWriteSize {
+		end := start + batchWriteSize
+		if end > len(writes) {
+			end = len(writes)
+		}
+
+		request := map[string][]types.WriteRequest{table: writes[start:end]}
+		for attempt := 0; len(request) > 0; attempt++ {
+			if attempt == batchWriteAttempts {
+				return &ThrottledError{Err: fmt.Errorf(
+					"%d items unprocessed after %d batch writes", len(request[table]), attempt,
+				)}
+			}
+			if attempt > 0 {
+				select {
+				case <-time.After(retryBackoff(attempt)):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			res, err := c.c.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: request})
+			if err != nil {
+				return err
+			}
+			request = res.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// retryBackoff returns the delay before the retry of an attempt, doubling
+// batchWriteBackoff on every attempt up to maxBatchWriteBackoff.
+func retryBackoff(attempt int) time.Duration {
+	backoff := batchWriteBackoff
+	for i := 1; i < attempt && backoff < maxBatchWriteBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBatchWriteBackoff {
+		backoff = maxBatchWriteBackoff
+	}
+	return backoff
+}
+
+// searchTerm returns the timestamps of the transactions of a user matching the term.
+// All index entries of the term are read: index sort keys are ordered by token
+// before timestamp, so a partial read would miss the newest matches of prefix
+// terms and the matches other terms are intersected with.
+func (c *Client) searchTerm(ctx context.Context, userID string, term SearchTerm) (map[string]bool, error) {
+	keyCond := expression.Key("user_id").Equal(expression.Value(userID)).
+		And(expression.Key("term").BeginsWith(term.indexPrefix()))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to make key expression: %w", err)
+	}
+
+	found := map[string]bool{}
+	paginator := dynamodb.NewQueryPaginator(c.c, &dynamodb.QueryInput{
+		TableName:                 aws.String(c.search),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	for paginator.HasMorePages() {
+		res, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var items []searchIndexItem
+		if err := attributevalue.UnmarshalListOfMaps(res.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to decode search index: %w", err)
+		}
+		for _, item := range items {
+			found[item.Timestamp] = true
+		}
+	}
+	return found, nil
+}
+
+// Search returns the newest transactions of a user matching all terms of the query.
+func (c *Client) Search(ctx context.Context, userID, q string, limit int) ([]Transaction, error) {
+	terms := ParseSearchQuery(q)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	var matches map[string]bool
+	for _, term := range terms {
+		found, err := c.searchTerm(ctx, userID, term)
+		if err != nil {
+			return nil, err
+		}
+		if matches == nil {
+			matches = found
+			continue
+		}
+		for ts := range matches {
+			if !found[ts] {
+				delete(matches, ts)
+			}
+		}
+	}
+
+	timestamps := make([]string, 0, len(matches))
+	for ts := range matches {
+		timestamps = append(timestamps, ts)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(timestamps)))
+	if len(timestamps) > limit {
+		timestamps = timestamps[:limit]
+	}
+
+	pks := make([]TransactionPK, len(timestamps))
+	for i, ts := range timestamps {
+		pks[i] = TransactionPK{UserID: userID, Timestamp: ts}
+	}
+	return c.BatchGet(ctx, pks)
+}
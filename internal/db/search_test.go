@@ -0,0 +1,84 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"Flat White, at Blue-Bottle!", []string{"flat", "white", "at", "blue", "bottle"}},
+		{"coffee COFFEE Coffee", []string{"coffee"}},
+		{"a b 42 x1", []string{"42", "x1"}},
+		{"Café Zürich", []string{"café", "zürich"}},
+		{strings.Repeat("a", 70), []string{strings.Repeat("a", 64)}},
+	}
+
+	for _, test := range tests {
+		if got := Tokenize(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestTransaction_SearchTokens(t *testing.T) {
+	tr := Transaction{Description: "Coffee beans", Counterparty: "Blue Bottle Coffee"}
+	want := []string{"coffee", "beans", "blue", "bottle"}
+	if got := tr.SearchTokens(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	want := []SearchTerm{
+		{Token: "blue", Prefix: false},
+		{Token: "coff", Prefix: true},
+	}
+	got := ParseSearchQuery(" Blue  coff* ")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got[0].indexPrefix() != "blue#" || got[1].indexPrefix() != "coff" {
+		t.Errorf("unexpected index prefixes: %q, %q", got[0].indexPrefix(), got[1].indexPrefix())
+	}
+
+	if terms := ParseSearchQuery("* - !"); len(terms) != 0 {
+		t.Errorf("expected no terms, got %v", terms)
+	}
+}
+
+func TestDifference(t *testing.T) {
+	before := []string{"blue", "bottle", "coffee"}
+	after := []string{"blue", "coffee", "beans"}
+
+	if got := difference(before, after); !reflect.DeepEqual(got, []string{"bottle"}) {
+		t.Errorf("expected removed tokens [bottle], got %v", got)
+	}
+	if got := difference(after, before); !reflect.DeepEqual(got, []string{"beans"}) {
+		t.Errorf("expected added tokens [beans], got %v", got)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, batchWriteBackoff},
+		{2, 2 * batchWriteBackoff},
+		{4, 8 * batchWriteBackoff},
+		{batchWriteAttempts, maxBatchWriteBackoff},
+		{100, maxBatchWriteBackoff},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempt); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"fmt"
+	"math"
+)
+
+// Uncategorized is the category of amounts without a category.
+const Uncategorized = "uncategorized"
+
+// ErrInvalidSplits is returned when the splits do not sum up to the transaction amount.
+var ErrInvalidSplits = invalid("splits do not sum up to the transaction amount")
+
+// Split is a line item of a transaction, e.g. one category of a card charge.
+type Split struct {
+	Amount   float64 `json:"amount"           dynamodbav:"amount"           validate:"required,gt=0"`
+	Category string  `json:"category"         dynamodbav:"category"         validate:"required"`
+	Note     string  `json:"note,omitempty"   dynamodbav:"note,omitempty"   validate:"max=256"`
+}
+
+// validateSplits checks that the splits sum up to the amount, to the cent.
+func (tr Transaction) validateSplits() error {
+	if len(tr.Splits) == 0 {
+		return nil
+	}
+
+	var sum float64
+	for _, s := range tr.Splits {
+		sum += s.Amount
+	}
+	if math.Round(sum*100) != math.Round(tr.Amount*100) {
+		return invalidField("splits", fmt.Errorf("%w: %v != %v", ErrInvalidSplits, sum, tr.Amount))
+	}
+	return nil
+}
+
+// CategoryAmounts returns the signed amount of the transaction per category.
+// Split transactions contribute the amount of each split to its category,
+// other transactions their full amount to the transaction category.
+func (tr Transaction) CategoryAmounts() map[string]float64 {
+	sign := 1.0
+	if tr.SignedAmount() < 0 {
+		sign = -1
+	}
+
+	amounts := map[string]float64{}
+	if len(tr.Splits) == 0 {
+		category := tr.Category
+		if category == "" {
+			category = Uncategorized
+		}
+		amounts[category] = sign * tr.Amount
+		return amounts
+	}
+	for _, s := range tr.Splits {
+		amounts[s.Category] += sign * s.Amount
+	}
+	return amounts
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTransaction_ValidateSplits(t *testing.T) {
+	tr := Transaction{
+		UserID:        "john",
+		Timestamp:     "2024-01-15T10:00:00.822373Z",
+		ID:            "t1",
+		Origin:        "web",
+		OperationType: OperationDebit,
+		Amount:        100.3,
+		Status:        StatusPosted,
+	}
+
+	tests := []struct {
+		name   string
+		splits []Split
+		err    error
+	}{
+		{"no splits", nil, nil},
+		{"splits summing up to amount", []Split{
+			{Amount: 60.1, Category: "groceries"},
+			{Amount: 40.2, Category: "household", Note: "detergent"},
+		}, nil},
+		{"splits not summing up to amount", []Split{
+			{Amount: 60.1, Category: "groceries"},
+			{Amount: 40.1, Category: "household"},
+		}, ErrInvalidSplits},
+	}
+
+	for _, test := range tests {
+		tr.Splits = test.splits
+		if err := tr.Validate(); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+		}
+	}
+
+	tr.Splits = []Split{{Amount: 100.3}}
+	if err := tr.Validate(); err == nil {
+		t.Errorf("expected error for split without category")
+	}
+}
+
+func TestCategoryStats_Add(t *testing.T) {
+	stats := CategoryStats{Categories: map[string]float64{}}
+
+	stats.Add(Transaction{OperationType: OperationDebit, Amount: 100, Splits: []Split{
+		{Amount: 70, Category: "groceries"},
+		{Amount: 30, Category: "household"},
+	}})
+	stats.Add(Transaction{OperationType: OperationDebit, Amount: 5, Splits: []Split{
+		{Amount: 5, Category: "groceries"},
+	}})
+	stats.Add(Transaction{OperationType: OperationCredit, Amount: 50})
+
+	want := map[string]float64{
+		"groceries":   -75,
+		"household":   -30,
+		Uncategorized: 50,
+	}
+	if !reflect.DeepEqual(stats.Categories, want) {
+		t.Errorf("expected %v, got %v", want, stats.Categories)
+	}
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+)
+
+// CategoryStats are the signed amounts of the transactions of a user per category.
+type CategoryStats struct {
+	UserID          string             `json:"user_id"`
+	TimestampPrefix string             `json:"ts"`
+	Categories      map[string]float64 `json:"categories"`
+}
+
+// Add adds the amounts of a transaction to the stats.
+func (s *CategoryStats) Add(tr Transaction) {
+	for category, amount := range tr.CategoryAmounts() {
+		s.Categories[category] += amount
+	}
+}
+
+// CategoryStats sums the transactions matching the request per category.
+// Only posted transactions are counted unless the request filters by another status.
+func (c *Client) CategoryStats(ctx context.Context, req UserListRequest) (CategoryStats, error) {
+	if err := req.Validate(); err != nil {
+		return CategoryStats{}, err
+	}
+	if req.Status == "" {
+		req.Status = StatusPosted
+	}
+
+	stats := CategoryStats{
+		UserID:          req.UserID,
+		TimestampPrefix: req.TimestampPrefix,
+		Categories:      map[string]float64{},
+	}
+	err := c.ForEach(ctx, req, func(tr Transaction) error {
+		stats.Add(tr)
+		return nil
+	})
+	if err != nil {
+		return CategoryStats{}, err
+	}
+	return stats, nil
+}
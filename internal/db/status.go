@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Transaction statuses
+const (
+	StatusPending = "pending"
+	StatusPosted  = "posted"
+	StatusFailed  = "failed"
+	StatusVoided  = "voided"
+)
+
+// ErrIllegalTransition is returned when a status transition is not allowed.
+var ErrIllegalTransition = conflict("illegal status transition")
+
+// statusTransitions lists the statuses each status can transition to.
+// Failed and voided transactions are final.
+var statusTransitions = map[string][]string{
+	StatusPending: {StatusPosted, StatusFailed, StatusVoided},
+	StatusPosted:  {StatusVoided},
+}
+
+// CanTransition reports whether a transaction can move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange records a status transition of a transaction.
+type StatusChange struct {
+	From string `json:"from" dynamodbav:"from"`
+	To   string `json:"to"   dynamodbav:"to"   validate:"required,oneof=pending posted failed voided"`
+	By   string `json:"by"   dynamodbav:"by"   validate:"required"`
+	At   string `json:"at"   dynamodbav:"at"`
+}
+
+// TransitionStatus moves the transaction identified by pk to a new status.
+// It returns ErrIllegalTransition if the current status cannot move to the new one,
+// including when the status was changed concurrently.
+func (c *Client) TransitionStatus(
+	ctx context.Context,
+	pk TransactionPK,
+	to, by string,
+) (Transaction, error) {
+	change := StatusChange{To: to, By: by, At: Timestamp()}
+	if err := validateStruct(&change); err != nil {
+		return Transaction{}, err
+	}
+
+	tr, err := c.Get(ctx, pk)
+	if err != nil {
+		return Transaction{}, err
+	}
+	change.From = tr.Status
+	if !CanTransition(change.From, change.To) {
+		return Transaction{}, fmt.Errorf("%w from %s to %s", ErrIllegalTransition, change.From, change.To)
+	}
+
+	changeAV, err := attributevalue.Marshal([]StatusChange{change})
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	// transactions created before statuses were introduced have no status and are posted
+	cond := "#status = :from"
+	if change.From == StatusPosted {
+		cond = "(#status = :from OR attribute_not_exists(#status))"
+	}
+
+	res, err := c.c.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(c.table),
+		Key:                 pk.ToAttributes(),
+		UpdateExpression:    aws.String("SET #status = :to, #history = list_append(if_not_exists(#history, :empty), :change)"),
+		ConditionExpression: aws.String(cond),
+		ExpressionAttributeNames: map[string]string{
+			"#status":  "status",
+			"#history": "status_history",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":from":   &types.AttributeValueMemberS{Value: change.From},
+			":to":     &types.AttributeValueMemberS{Value: change.To},
+			":change": changeAV,
+			":empty":  &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return Transaction{}, fmt.Errorf("%w: status was changed concurrently", ErrIllegalTransition)
+	}
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	if err := attributevalue.UnmarshalMap(res.Attributes, &tr); err != nil {
+		return Transaction{}, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	return tr, nil
+}
@@ -0,0 +1,26 @@
+package db
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPending, StatusPosted, true},
+		{StatusPending, StatusFailed, true},
+		{StatusPending, StatusVoided, true},
+		{StatusPosted, StatusVoided, true},
+		{StatusPosted, StatusPending, false},
+		{StatusPosted, StatusFailed, false},
+		{StatusPosted, StatusPosted, false},
+		{StatusFailed, StatusPosted, false},
+		{StatusVoided, StatusPosted, false},
+	}
+
+	for _, test := range tests {
+		if got := CanTransition(test.from, test.to); got != test.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// AttributesFromStreamImage converts a DynamoDB stream image, as delivered to Lambda,
+// to a DynamoDB AttributeValue map that can be decoded with attributevalue.UnmarshalMap.
+func AttributesFromStreamImage(
+	image map[string]events.DynamoDBAttributeValue,
+) map[string]types.AttributeValue {
+	if image == nil {
+		return nil
+	}
+
+	attrs := make(map[string]types.AttributeValue, len(image))
+	for name, av := range image {
+		attrs[name] = attributeFromStream(av)
+	}
+	return attrs
+}
+
+// attributeFromStream converts a single stream attribute value.
+func attributeFromStream(av events.DynamoDBAttributeValue) types.AttributeValue {
+	switch av.DataType() {
+	case events.DataTypeString:
+		return &types.AttributeValueMemberS{Value: av.String()}
+	case events.DataTypeNumber:
+		return &types.AttributeValueMemberN{Value: av.Number()}
+	case events.DataTypeBinary:
+		return &types.AttributeValueMemberB{Value: av.Binary()}
+	case events.DataTypeBoolean:
+		return &types.AttributeValueMemberBOOL{Value: av.Boolean()}
+	case events.DataTypeStringSet:
+		return &types.AttributeValueMemberSS{Value: av.StringSet()}
+	case events.DataTypeNumberSet:
+		return &types.AttributeValueMemberNS{Value: av.NumberSet()}
+	case events.DataTypeBinarySet:
+		return &types.AttributeValueMemberBS{Value: av.BinarySet()}
+	case events.DataTypeList:
+		list := av.List()
+		values := make([]types.AttributeValue, len(list))
+		for i, item := range list {
+			values[i] = attributeFromStream(item)
+		}
+		return &types.AttributeValueMemberL{Value: values}
+	case events.DataTypeMap:
+		return &types.AttributeValueMemberM{Value: AttributesFromStreamImage(av.Map())}
+	default:
+		return &types.AttributeValueMemberNULL{Value: true}
+	}
+}
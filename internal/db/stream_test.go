@@ -0,0 +1,50 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+)
+
+func TestAttributesFromStreamImage(t *testing.T) {
+	raw := `{
+		"user_id": {"S": "john"},
+		"hold_id": {"S": "h1"},
+		"origin": {"S": "web"},
+		"amount": {"N": "12.5"},
+		"status": {"S": "active"},
+		"created_at": {"S": "2024-01-15T10:00:00.822373Z"},
+		"expires_at": {"N": "1705312800"},
+		"transaction": {"M": {"user_id": {"S": "john"}, "ts": {"S": "2024"}}}
+	}`
+
+	var image map[string]events.DynamoDBAttributeValue
+	if err := json.Unmarshal([]byte(raw), &image); err != nil {
+		t.Fatal(err)
+	}
+
+	var h Hold
+	if err := attributevalue.UnmarshalMap(AttributesFromStreamImage(image), &h); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Hold{
+		UserID:      "john",
+		ID:          "h1",
+		Origin:      "web",
+		Amount:      12.5,
+		Status:      HoldActive,
+		CreatedAt:   "2024-01-15T10:00:00.822373Z",
+		ExpiresAt:   1705312800,
+		Transaction: &TransactionPK{UserID: "john", Timestamp: "2024"},
+	}
+	if h.Transaction == nil || *h.Transaction != *want.Transaction {
+		t.Fatalf("expected transaction %v, got %v", want.Transaction, h.Transaction)
+	}
+	h.Transaction = want.Transaction
+	if h != want {
+		t.Errorf("expected %+v, got %+v", want, h)
+	}
+}
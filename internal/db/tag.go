@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// CategoryIndex is the name of the transactions index keyed by user_id#category and ts.
+const CategoryIndex = "user_category-ts-index"
+
+// tagIndexItem references a tagged transaction in the tags table,
+// which is keyed by user_id#tag and ts.
+type tagIndexItem struct {
+	UserTag   string `dynamodbav:"user_tag"`
+	Timestamp string `dynamodbav:"ts"`
+	UserID    string `dynamodbav:"user_id"`
+}
+
+// tagWrites returns the writes indexing the tags of a transaction.
+func (c *Client) tagWrites(tr Transaction) ([]types.TransactWriteItem, error) {
+	writes := make([]types.TransactWriteItem, 0, len(tr.Tags))
+	for _, tag := range tr.Tags {
+		item, err := attributevalue.MarshalMap(tagIndexItem{
+			UserTag:   indexKey(tr.UserID, tag),
+			Timestamp: tr.Timestamp,
+			UserID:    tr.UserID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes, types.TransactWriteItem{
+			Put: &types.Put{TableName: aws.String(c.tags), Item: item},
+		})
+	}
+	return writes, nil
+}
+
+// tagDeletes returns the writes removing the given tags of a transaction from the index.
+func (c *Client) tagDeletes(tr Transaction, tags []string) []types.TransactWriteItem {
+	writes := make([]types.TransactWriteItem, 0, len(tags))
+	for _, tag := range tags {
+		writes = append(writes, types.TransactWriteItem{
+			Delete: &types.Delete{
+				TableName: aws.String(c.tags),
+				Key: map[string]types.AttributeValue{
+					"user_tag": &types.AttributeValueMemberS{Value: indexKey(tr.UserID, tag)},
+					"ts":       &types.AttributeValueMemberS{Value: tr.Timestamp},
+				},
+			},
+		})
+	}
+	return writes
+}
+
+// queryTag runs a single page of a request listing the transactions with a tag.
+// The tags table only references transactions, so the remaining filters of
+// the request are applied to the fetched transactions.
+func (c *Client) queryTag(ctx context.Context, req UserListRequest) (ListResponse, error) {
+	keyCond := expression.Key("user_tag").Equal(expression.Value(indexKey(req.UserID, req.Tag)))
+	expr, err := expression.NewBuilder().WithKeyCondition(req.withTimestampKey(keyCond)).Build()
+	if err != nil {
+		return ListResponse{}, fmt.Errorf("failed to make key expression: %w", err)
+	}
+
+	after, err := TransactionPKFromBase64(req.After)
+	if err != nil {
+		return ListResponse{}, fmt.Errorf("failed to decode last evaluated key: %w", err)
+	}
+
+	res, err := c.c.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(c.tags),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		Limit:                     req.Limit,
+		ExclusiveStartKey:         after.ToAttributes(),
+	})
+	if err != nil {
+		return ListResponse{}, err
+	}
+
+	log.Printf("last evaluated key is: %v", res.LastEvaluatedKey)
+
+	var refs []tagIndexItem
+	if err := attributevalue.UnmarshalListOfMaps(res.Items, &refs); err != nil {
+		return ListResponse{}, fmt.Errorf("failed to decode tag index: %w", err)
+	}
+	pks := make([]TransactionPK, len(refs))
+	for i, ref := range refs {
+		pks[i] = TransactionPK{UserID: ref.UserID, Timestamp: ref.Timestamp}
+	}
+
+	found, err := c.BatchGet(ctx, pks)
+	if err != nil {
+		return ListResponse{}, err
+	}
+
+	resp := ListResponse{Items: []Transaction{}}
+	for _, t := range found {
+		if req.Matches(t) {
+			resp.Items = append(resp.Items, t)
+		}
+	}
+
+	pk := TransactionPKFromAttributes(res.LastEvaluatedKey)
+	if resp.Cursor, err = pk.ToBase64(); err != nil {
+		return ListResponse{}, err
+	}
+	return resp, nil
+}
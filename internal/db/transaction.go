@@ -7,14 +7,16 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 )
 
 // TransactionPK represents the primary key of a transaction.
+// When used as a cursor it also holds the key attributes of the index the query ran on.
 type TransactionPK struct {
-	UserID    string `json:"user_id,omitempty" dynamodbav:"user_id"`
-	Timestamp string `json:"ts,omitempty"      dynamodbav:"ts"`
+	UserID       string `json:"user_id,omitempty"       dynamodbav:"user_id"`
+	Timestamp    string `json:"ts,omitempty"            dynamodbav:"ts"`
+	UserCategory string `json:"user_category,omitempty" dynamodbav:"user_category,omitempty"`
+	UserTag      string `json:"user_tag,omitempty"      dynamodbav:"user_tag,omitempty"`
 }
 
 // TransactionPKFromAttributes converts DynamoDB AttributeValue map to a TransactionPK.
@@ -24,8 +26,10 @@ func TransactionPKFromAttributes(attrs map[string]types.AttributeValue) Transact
 	}
 
 	return TransactionPK{
-		UserID:    attrs["user_id"].(*types.AttributeValueMemberS).Value,
-		Timestamp: attrs["ts"].(*types.AttributeValueMemberS).Value,
+		UserID:       stringAttr(attrs, "user_id"),
+		Timestamp:    stringAttr(attrs, "ts"),
+		UserCategory: stringAttr(attrs, "user_category"),
+		UserTag:      stringAttr(attrs, "user_tag"),
 	}
 }
 
@@ -53,10 +57,19 @@ func (pk TransactionPK) ToAttributes() map[string]types.AttributeValue {
 		return nil
 	}
 
-	return map[string]types.AttributeValue{
-		"user_id": &types.AttributeValueMemberS{Value: pk.UserID},
-		"ts":      &types.AttributeValueMemberS{Value: pk.Timestamp},
+	attrs := map[string]types.AttributeValue{
+		"ts": &types.AttributeValueMemberS{Value: pk.Timestamp},
 	}
+	if pk.UserID != "" {
+		attrs["user_id"] = &types.AttributeValueMemberS{Value: pk.UserID}
+	}
+	if pk.UserCategory != "" {
+		attrs["user_category"] = &types.AttributeValueMemberS{Value: pk.UserCategory}
+	}
+	if pk.UserTag != "" {
+		attrs["user_tag"] = &types.AttributeValueMemberS{Value: pk.UserTag}
+	}
+	return attrs
 }
 
 // ToBase64 converts a TransactionPK to a base64 encoded string.
@@ -72,19 +85,61 @@ func (pk TransactionPK) ToBase64() (string, error) {
 	return base64.StdEncoding.EncodeToString(b), nil
 }
 
+// Operation types of a transaction
+const (
+	OperationCredit = "credit"
+	OperationDebit  = "debit"
+	// OperationRefund credits back (part of) a debit referenced by RefundOf.
+	OperationRefund = "refund"
+)
+
 // Transaction represents a transaction model
 type Transaction struct {
 	UserID        string  `json:"user_id"        dynamodbav:"user_id"        validate:"required"`
 	Timestamp     string  `json:"ts"             dynamodbav:"ts"             validate:"required"`
 	ID            string  `json:"tr_id"                                      validate:"required"       danamodbav:"tr_id"`
 	Origin        string  `json:"origin"         dynamodbav:"origin"         validate:"required"`
-	OperationType string  `json:"operation_type" dynamodbav:"operation_type" validate:"required"`
+	OperationType string  `json:"operation_type" dynamodbav:"operation_type" validate:"required,oneof=credit debit refund"`
 	Amount        float64 `json:"amount"         dynamodbav:"amount"         validate:"required,gte=0"`
+	Status        string  `json:"status"         dynamodbav:"status"         validate:"required,oneof=pending posted failed voided"`
+	// StatusHistory records the status transitions of the transaction.
+	StatusHistory []StatusChange `json:"status_history,omitempty" dynamodbav:"status_history,omitempty"`
+	// RefundOf is the primary key of the original transaction of a refund.
+	RefundOf *TransactionPK `json:"refund_of,omitempty" dynamodbav:"refund_of,omitempty"`
+	// RefundedAmount is the cumulative amount refunded from the transaction.
+	RefundedAmount float64 `json:"refunded_amount,omitempty" dynamodbav:"refunded_amount,omitempty"`
+	// RefundKeys are the primary keys of the refunds of the transaction.
+	RefundKeys []TransactionPK `json:"-" dynamodbav:"refund_keys,omitempty"`
+	// Splits break the amount down into line items, they must sum up to the amount.
+	Splits       []Split  `json:"splits,omitempty"   dynamodbav:"splits,omitempty"   validate:"omitempty,max=50,dive"`
+	Category     string   `json:"category,omitempty" dynamodbav:"category,omitempty" validate:"max=64"`
+	Tags         []string `json:"tags,omitempty"     dynamodbav:"tags,omitempty"     validate:"omitempty,max=10,unique,dive,required,max=64"`
+	Description  string   `json:"description,omitempty"  dynamodbav:"description,omitempty"  validate:"max=512"`
+	Counterparty string   `json:"counterparty,omitempty" dynamodbav:"counterparty,omitempty" validate:"max=128"`
+	// ExternalID is the ID of the transaction in an external system, such as
+	// the FITID of an imported bank statement transaction.
+	ExternalID string `json:"external_id,omitempty" dynamodbav:"external_id,omitempty" validate:"max=255"`
+	// Reference is the reference of the payment, such as an end-to-end ID.
+	Reference string `json:"reference,omitempty" dynamodbav:"reference,omitempty" validate:"max=140"`
+	// BookingDate and ValueDate are the dates a bank booked the transaction
+	// and the funds became available, as in imported statements.
+	BookingDate string `json:"booking_date,omitempty" dynamodbav:"booking_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	ValueDate   string `json:"value_date,omitempty"   dynamodbav:"value_date,omitempty"   validate:"omitempty,datetime=2006-01-02"`
+	// DuplicateOf is the primary key of the transaction this one was flagged
+	// as a duplicate of on creation.
+	DuplicateOf *TransactionPK `json:"duplicate_of,omitempty" dynamodbav:"duplicate_of,omitempty"`
+	// Metadata holds external references such as order IDs or invoice numbers.
+	Metadata map[string]string `json:"metadata,omitempty" dynamodbav:"metadata,omitempty" validate:"max=20,dive,keys,max=64,endkeys,max=256"`
+	// UserCategory is the partition key of the category index, user_id#category.
+	UserCategory string `json:"-" dynamodbav:"user_category,omitempty"`
 }
 
+// TimestampLayout is the ISO 8601 layout used for the sort key.
+const TimestampLayout = "2006-01-02T15:04:05.999999Z"
+
 // Timestamp returns the current timestamp in ISO 8601 format.
 func Timestamp() string {
-	return time.Now().Format("2006-01-02T15:04:05.999999Z")
+	return time.Now().UTC().Format(TimestampLayout)
 }
 
 // Convert tr to DynamoDB AttributeValue map
@@ -95,11 +150,36 @@ func (tr *Transaction) SetDefaults() {
 	if tr.Timestamp == "" {
 		tr.Timestamp = Timestamp()
 	}
+	if tr.Status == "" {
+		tr.Status = StatusPosted
+	}
+	tr.UserCategory = ""
+	if tr.Category != "" {
+		tr.UserCategory = indexKey(tr.UserID, tr.Category)
+	}
 }
 
 // Validate validates the transaction
 func (tr Transaction) Validate() error {
-	// Create a new validator instance.
-	v := validator.New()
-	return v.Struct(&tr)
+	if err := validateStruct(&tr); err != nil {
+		return err
+	}
+	if err := validateMetadataKeys(tr.Metadata); err != nil {
+		return err
+	}
+	return tr.validateSplits()
+}
+
+// SignedAmount returns the amount with the sign it contributes to the balance:
+// credits are positive and debits are negative.
+func (tr Transaction) SignedAmount() float64 {
+	if tr.OperationType == OperationDebit {
+		return -tr.Amount
+	}
+	return tr.Amount
+}
+
+// PK returns the primary key of the transaction.
+func (tr Transaction) PK() TransactionPK {
+	return TransactionPK{UserID: tr.UserID, Timestamp: tr.Timestamp}
 }
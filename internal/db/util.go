@@ -2,6 +2,11 @@ package db
 
 import (
 	"fmt"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 // stringToInt32Ptr converts a string to an int32 pointer.
@@ -18,3 +23,47 @@ func stringToInt32Ptr(s string) (*int32, error) {
 
 	return &i, nil
 }
+
+// getenv returns the value of the environment variable or def if it is empty.
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// andAll joins the conditions with AND. It reports false if there are no conditions.
+func andAll(conds []expression.ConditionBuilder) (expression.ConditionBuilder, bool) {
+	switch len(conds) {
+	case 0:
+		return expression.ConditionBuilder{}, false
+	case 1:
+		return conds[0], true
+	default:
+		return expression.And(conds[0], conds[1], conds[2:]...), true
+	}
+}
+
+// stringAttr returns the value of a string attribute, or an empty string if
+// the attribute is missing or not a string.
+func stringAttr(attrs map[string]types.AttributeValue, name string) string {
+	if av, ok := attrs[name].(*types.AttributeValueMemberS); ok {
+		return av.Value
+	}
+	return ""
+}
+
+// indexKey joins a user ID and a value into a partition key of an index, e.g. john#groceries.
+func indexKey(userID, value string) string {
+	return userID + "#" + value
+}
+
+// sortedKeys returns the keys of the map in order, for deterministic expressions.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,201 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"transactions/internal/db"
+)
+
+// dateLayout is the layout of the dates of journal entries.
+const dateLayout = "2006-01-02"
+
+// ErrInvalidAccounts is returned for account mappings that cannot be written to journals.
+var ErrInvalidAccounts = errors.New("invalid account mapping")
+
+// DefaultAccounts is the account mapping of accounting exports when none is configured.
+var DefaultAccounts = Accounts{
+	Asset:    "Assets:Checking",
+	Currency: "USD",
+	Expenses: "Expenses:Uncategorized",
+	Income:   "Income:Uncategorized",
+}
+
+// Accounts maps transactions to the accounts of accounting journals. Every transaction
+// is booked between the asset account and the account of its category, origin or
+// operation type, whichever is mapped first, or the expenses or income account.
+type Accounts struct {
+	// Asset is the account holding the funds of the user.
+	Asset string `json:"asset,omitempty"`
+	// Currency is the commodity of the amounts.
+	Currency   string            `json:"currency,omitempty"`
+	Categories map[string]string `json:"categories,omitempty"`
+	Origins    map[string]string `json:"origins,omitempty"`
+	Operations map[string]string `json:"operations,omitempty"`
+	// Expenses and Income are the accounts of the debits and credits that are not mapped.
+	Expenses string `json:"expenses,omitempty"`
+	Income   string `json:"income,omitempty"`
+}
+
+// AccountsFromEnv returns the account mapping of the EXPORT_ACCOUNTS
+// environment variable, a JSON object, or DefaultAccounts when empty.
+func AccountsFromEnv() (Accounts, error) {
+	s := os.Getenv("EXPORT_ACCOUNTS")
+	if s == "" {
+		return DefaultAccounts, nil
+	}
+
+	var a Accounts
+	if err := json.Unmarshal([]byte(s), &a); err != nil {
+		return Accounts{}, fmt.Errorf("failed to decode EXPORT_ACCOUNTS: %w", err)
+	}
+	a = a.withDefaults()
+	if err := a.Validate(); err != nil {
+		return Accounts{}, err
+	}
+	return a, nil
+}
+
+// withDefaults returns the mapping with the accounts of DefaultAccounts in place of empty ones.
+func (a Accounts) withDefaults() Accounts {
+	if a.Asset == "" {
+		a.Asset = DefaultAccounts.Asset
+	}
+	if a.Currency == "" {
+		a.Currency = DefaultAccounts.Currency
+	}
+	if a.Expenses == "" {
+		a.Expenses = DefaultAccounts.Expenses
+	}
+	if a.Income == "" {
+		a.Income = DefaultAccounts.Income
+	}
+	return a
+}
+
+// Validate checks the account names can be written to journals: they must not
+// be empty nor contain tabs, line breaks or consecutive spaces, which separate
+// account names from amounts.
+func (a Accounts) Validate() error {
+	for _, name := range a.Names() {
+		if name == "" || strings.ContainsAny(name, "\t\r\n") || strings.Contains(name, "  ") {
+			return fmt.Errorf("%w: account %q", ErrInvalidAccounts, name)
+		}
+	}
+	if a.Currency == "" || strings.ContainsAny(a.Currency, " \t\r\n") {
+		return fmt.Errorf("%w: currency %q", ErrInvalidAccounts, a.Currency)
+	}
+	return nil
+}
+
+// Names returns the sorted names of the accounts of the mapping.
+func (a Accounts) Names() []string {
+	set := map[string]bool{a.Asset: true, a.Expenses: true, a.Income: true}
+	for _, m := range []map[string]string{a.Categories, a.Origins, a.Operations} {
+		for _, name := range m {
+			set[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Account returns the account balancing the asset account for an amount
+// of the category in a transaction.
+func (a Accounts) Account(category string, tr db.Transaction) string {
+	if name, ok := a.Categories[category]; ok && category != "" {
+		return name
+	}
+	if name, ok := a.Origins[tr.Origin]; ok {
+		return name
+	}
+	if name, ok := a.Operations[tr.OperationType]; ok {
+		return name
+	}
+	if tr.OperationType == db.OperationDebit {
+		return a.Expenses
+	}
+	return a.Income
+}
+
+// posting is an amount booked to an account.
+type posting struct {
+	Account string
+	Amount  float64
+}
+
+// postings returns the postings of a transaction: the signed amount to the asset
+// account, balanced by the amount of every split or the whole amount. Amounts are
+// rounded to cents, as they are written, and the last split takes the remainder of
+// the rounding so that the postings balance.
+func (a Accounts) postings(tr db.Transaction) []posting {
+	sign := int64(1)
+	if tr.OperationType == db.OperationDebit {
+		sign = -1
+	}
+	total := toCents(tr.SignedAmount())
+
+	var ps []posting
+	if len(tr.Splits) == 0 {
+		ps = append(ps, posting{a.Account(tr.Category, tr), fromCents(-total)})
+	}
+	remainder := -total
+	for i, s := range tr.Splits {
+		amount := -sign * toCents(s.Amount)
+		if i == len(tr.Splits)-1 {
+			amount = remainder
+		}
+		remainder -= amount
+		ps = append(ps, posting{a.Account(s.Category, tr), fromCents(amount)})
+	}
+	return append(ps, posting{a.Asset, fromCents(total)})
+}
+
+// toCents returns an amount in cents, rounded.
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// fromCents returns an amount of cents.
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// booked reports whether the transaction belongs to accounting journals,
+// which leave out failed and voided transactions.
+func booked(tr db.Transaction) bool {
+	return tr.Status != db.StatusFailed && tr.Status != db.StatusVoided
+}
+
+// bookingDate returns the date a transaction is booked on in journals,
+// its booking date if any or the date of its timestamp.
+func bookingDate(tr db.Transaction) (time.Time, error) {
+	if tr.BookingDate != "" {
+		return time.Parse(dateLayout, tr.BookingDate)
+	}
+	if len(tr.Timestamp) < len(dateLayout) {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", tr.Timestamp)
+	}
+	return time.Parse(dateLayout, tr.Timestamp[:len(dateLayout)])
+}
+
+// oneLine replaces the line breaks and tabs of s with spaces.
+func oneLine(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, s)
+}
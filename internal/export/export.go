@@ -0,0 +1,204 @@
+// Package export writes transactions in file formats for spreadsheets and other tools.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"transactions/internal/db"
+)
+
+// Format is an export file format.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	// Accounting formats, booking transactions to the accounts of an account mapping
+	FormatLedger    Format = "ledger"
+	FormatBeancount Format = "beancount"
+	FormatIIF       Format = "iif"
+)
+
+// ErrUnknownFormat is returned for formats without a writer.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Columns are the columns of CSV exports, in order.
+var Columns = []string{
+	"user_id",
+	"ts",
+	"tr_id",
+	"origin",
+	"operation_type",
+	"amount",
+	"status",
+	"category",
+	"tags",
+	"description",
+	"counterparty",
+}
+
+// ParseFormat parses an export format, csv when empty.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "":
+		return FormatCSV, nil
+	case FormatCSV, FormatNDJSON, FormatLedger, FormatBeancount, FormatIIF:
+		return f, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownFormat, s)
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatLedger, FormatBeancount, FormatIIF:
+		return "text/plain; charset=utf-8"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// Filename returns the name of the file exporting the transactions of
+// the user in the range, e.g. transactions-john-2023-01-01-2023-12-31.csv.
+func Filename(userID, from, to string, f Format) string {
+	parts := []string{"transactions", userID}
+	for _, part := range []string{from, to} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	name := strings.Join(parts, "-")
+	// keep the name safe for the quoted content disposition parameter
+	name = strings.Map(func(r rune) rune {
+		if r == '"' || r == '\\' || r == '/' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	return name + "." + string(f)
+}
+
+// ContentDisposition returns the content disposition header of an export file.
+func ContentDisposition(filename string) string {
+	return fmt.Sprintf("attachment; filename=%q", filename)
+}
+
+// Writer writes transactions to a file.
+type Writer interface {
+	// Write writes a transaction.
+	Write(tr db.Transaction) error
+	// Flush writes any buffered data and reports write errors.
+	Flush() error
+}
+
+// headerWriter is a writer starting with a header, which is skipped
+// when the output continues the one of another writer.
+type headerWriter interface {
+	Writer
+	skipHeader()
+}
+
+// NewWriter returns a writer of the format. The accounting formats book
+// transactions to the accounts.
+func NewWriter(w io.Writer, f Format, accounts Accounts) (Writer, error) {
+	switch f {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatNDJSON:
+		return NewNDJSONWriter(w), nil
+	case FormatLedger:
+		return NewLedgerWriter(w, accounts), nil
+	case FormatBeancount:
+		return NewBeancountWriter(w, accounts), nil
+	case FormatIIF:
+		return NewIIFWriter(w, accounts), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, f)
+}
+
+// CSVWriter writes transactions as CSV rows of Columns, starting with a header row.
+type CSVWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVWriter returns a CSV writer.
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+// Write writes the header row before the first transaction, then the transaction.
+func (w *CSVWriter) Write(tr db.Transaction) error {
+	if !w.header {
+		if err := w.WriteHeader(); err != nil {
+			return err
+		}
+	}
+	return w.w.Write(Record(tr))
+}
+
+// WriteHeader writes the header row, unless it is already written.
+func (w *CSVWriter) WriteHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	return w.w.Write(Columns)
+}
+
+func (w *CSVWriter) skipHeader() { w.header = true }
+
+// Flush writes the header row when no transaction was written and flushes the rows.
+func (w *CSVWriter) Flush() error {
+	if err := w.WriteHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Record returns the CSV record of the transaction in the order of Columns.
+// Tags are separated by semicolons.
+func Record(tr db.Transaction) []string {
+	return []string{
+		tr.UserID,
+		tr.Timestamp,
+		tr.ID,
+		tr.Origin,
+		tr.OperationType,
+		strconv.FormatFloat(tr.Amount, 'f', -1, 64),
+		tr.Status,
+		tr.Category,
+		strings.Join(tr.Tags, ";"),
+		tr.Description,
+		tr.Counterparty,
+	}
+}
+
+// NDJSONWriter writes transactions as newline delimited JSON objects.
+type NDJSONWriter struct {
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter returns a NDJSON writer.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{enc: json.NewEncoder(w)}
+}
+
+// Write writes the transaction on a line.
+func (w *NDJSONWriter) Write(tr db.Transaction) error {
+	return w.enc.Encode(tr)
+}
+
+// Flush does nothing, as lines are written unbuffered.
+func (w *NDJSONWriter) Flush() error {
+	return nil
+}
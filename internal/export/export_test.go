@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"transactions/internal/db"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatCSV, false},
+		{"csv", FormatCSV, false},
+		{"NDJSON", FormatNDJSON, false},
+		{"xlsx", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("ParseFormat(%q) error = %v, want ErrUnknownFormat", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFilename(t *testing.T) {
+	if got := Filename("john", "2023-01-01", "2023-12-31", FormatCSV); got != "transactions-john-2023-01-01-2023-12-31.csv" {
+		t.Errorf("unexpected filename %q", got)
+	}
+	if got := Filename(`jo"hn`, "", "", FormatNDJSON); got != "transactions-jo_hn.ndjson" {
+		t.Errorf("unexpected filename %q", got)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+	trs := []db.Transaction{
+		{
+			UserID:        "john",
+			Timestamp:     "2023-01-02T10:00:00Z",
+			ID:            "1",
+			Origin:        "web",
+			OperationType: db.OperationDebit,
+			Amount:        12.5,
+			Status:        db.StatusPosted,
+			Tags:          []string{"food", "work"},
+			Description:   `Lunch, "The Cafe"`,
+		},
+		{UserID: "john", Timestamp: "2023-01-03T10:00:00Z", Description: "two\nlines"},
+	}
+	for _, tr := range trs {
+		if err := w.Write(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"user_id,ts,tr_id,origin,operation_type,amount,status,category,tags,description,counterparty",
+		`john,2023-01-02T10:00:00Z,1,web,debit,12.5,posted,,food;work,"Lunch, ""The Cafe""",`,
+		"john,2023-01-03T10:00:00Z,,,,0,,,,\"two\nlines\",",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Errorf("unexpected csv:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestCSVWriter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != strings.Join(Columns, ",")+"\n" {
+		t.Errorf("expected header row only, got %q", buf.String())
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatNDJSON, DefaultAccounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2"} {
+		if err := w.Write(db.Transaction{UserID: "john", ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"tr_id":"2"`) {
+		t.Errorf("unexpected ndjson: %q", buf.String())
+	}
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"transactions/internal/db"
+)
+
+// iifHeader are the header rows of IIF transactions, defining the columns of the rows.
+var iifHeader = [][]string{
+	{"!TRNS", "TRNSID", "TRNSTYPE", "DATE", "ACCNT", "NAME", "AMOUNT", "DOCNUM", "MEMO", "CLEAR"},
+	{"!SPL", "SPLID", "TRNSTYPE", "DATE", "ACCNT", "NAME", "AMOUNT", "DOCNUM", "MEMO", "CLEAR"},
+	{"!ENDTRNS"},
+}
+
+// IIFWriter writes transactions as QuickBooks IIF transactions, starting with the
+// header rows. Every transaction is a TRNS row booking the amount to the asset
+// account and SPL rows balancing it. Failed and voided transactions are left out.
+type IIFWriter struct {
+	w        *bufio.Writer
+	accounts Accounts
+	header   bool
+}
+
+// NewIIFWriter returns an IIF writer booking transactions to the accounts.
+func NewIIFWriter(w io.Writer, accounts Accounts) *IIFWriter {
+	return &IIFWriter{w: bufio.NewWriter(w), accounts: accounts.withDefaults()}
+}
+
+// Write writes the header rows before the first transaction, then the rows of the transaction.
+func (w *IIFWriter) Write(tr db.Transaction) error {
+	if !booked(tr) {
+		return nil
+	}
+	date, err := bookingDate(tr)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader(); err != nil {
+		return err
+	}
+
+	trnsType := "DEPOSIT"
+	if tr.OperationType == db.OperationDebit {
+		trnsType = "CHECK"
+	}
+	clear := "Y"
+	if tr.Status == db.StatusPending {
+		clear = "N"
+	}
+	docNum := tr.ExternalID
+	if docNum == "" {
+		docNum = tr.Reference
+	}
+
+	postings := w.accounts.postings(tr)
+	// the asset posting comes last, IIF transactions start with it
+	asset := postings[len(postings)-1]
+	rows := [][]string{{"TRNS", "", trnsType, date.Format("01/02/2006"), asset.Account, tr.Counterparty,
+		iifAmount(asset.Amount), docNum, tr.Description, clear}}
+	for _, p := range postings[:len(postings)-1] {
+		rows = append(rows, []string{"SPL", "", trnsType, date.Format("01/02/2006"), p.Account, tr.Counterparty,
+			iifAmount(p.Amount), docNum, tr.Description, clear})
+	}
+	rows = append(rows, []string{"ENDTRNS"})
+	return w.writeRows(rows)
+}
+
+// WriteHeader writes the header rows, unless they are already written.
+func (w *IIFWriter) WriteHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	return w.writeRows(iifHeader)
+}
+
+func (w *IIFWriter) skipHeader() { w.header = true }
+
+// Flush writes the header rows when no transaction was written and flushes the rows.
+func (w *IIFWriter) Flush() error {
+	if err := w.WriteHeader(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// writeRows writes tab separated rows ending with CRLF, as QuickBooks expects.
+func (w *IIFWriter) writeRows(rows [][]string) error {
+	for _, row := range rows {
+		for i, field := range row {
+			if i > 0 {
+				w.w.WriteByte('\t')
+			}
+			w.w.WriteString(strings.ReplaceAll(oneLine(field), `"`, "'"))
+		}
+		if _, err := w.w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iifAmount formats an amount to the cent.
+func iifAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,146 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"transactions/internal/db"
+	"transactions/internal/store"
+)
+
+// DefaultChunkPages is the number of query pages an export job processes per chunk.
+const DefaultChunkPages = 10
+
+// Pager queries a page of transactions.
+type Pager interface {
+	Query(ctx context.Context, req db.UserListRequest) (db.ListResponse, error)
+}
+
+// Worker processes export jobs in resumable chunks. Every chunk is written to
+// the store as a part of the export, and the parts are joined into the
+// export file once the last page is written.
+type Worker struct {
+	Pager Pager
+	Store store.Store
+	// ChunkPages is the number of query pages per chunk, DefaultChunkPages if not set.
+	ChunkPages int
+	// Accounts is the account mapping of accounting formats.
+	Accounts Accounts
+}
+
+// partKey returns the store key of a part of the export.
+func partKey(job db.ExportJob, part int) string {
+	return fmt.Sprintf("exports/%s/part-%06d.%s", job.ID, part, job.Format)
+}
+
+// fileKey returns the store key of the export file.
+func fileKey(job db.ExportJob) string {
+	return fmt.Sprintf("exports/%s/%s", job.ID, Filename(job.UserID, job.From, job.To, Format(job.Format)))
+}
+
+// newPartWriter returns a writer of a part of the export.
+// Only the first part of an export has the header.
+func newPartWriter(w io.Writer, f Format, accounts Accounts, part int) (Writer, error) {
+	pw, err := NewWriter(w, f, accounts)
+	if err != nil {
+		return nil, err
+	}
+	if hw, ok := pw.(headerWriter); ok && part > 0 {
+		hw.skipHeader()
+	}
+	return pw, nil
+}
+
+// Step processes the next chunk of the job and returns the job with its
+// progress, to be checkpointed by the caller. Steps of a job are idempotent:
+// a chunk processed again from the same checkpoint overwrites the same part.
+func (w Worker) Step(ctx context.Context, job db.ExportJob) (db.ExportJob, error) {
+	if job.IsDone() {
+		return job, nil
+	}
+
+	var buf bytes.Buffer
+	pw, err := newPartWriter(&buf, Format(job.Format), w.Accounts, job.Parts)
+	if err != nil {
+		return job, err
+	}
+
+	pages := w.ChunkPages
+	if pages <= 0 {
+		pages = DefaultChunkPages
+	}
+
+	req := job.ListRequest()
+	count := 0
+	for i := 0; i < pages; i++ {
+		resp, err := w.Pager.Query(ctx, req)
+		if err != nil {
+			return job, fmt.Errorf("failed to query page: %w", err)
+		}
+		for _, tr := range resp.Items {
+			if err := pw.Write(tr); err != nil {
+				return job, err
+			}
+		}
+		count += len(resp.Items)
+		req.After = resp.Cursor
+		if req.After == "" {
+			break
+		}
+	}
+	if err := pw.Flush(); err != nil {
+		return job, err
+	}
+
+	if err := w.Store.Put(ctx, partKey(job, job.Parts), &buf); err != nil {
+		return job, fmt.Errorf("failed to store part: %w", err)
+	}
+
+	job.Status = db.ExportRunning
+	job.Parts++
+	job.Count += count
+	job.Cursor = req.After
+	job.Attempts = 0
+	job.Error = ""
+
+	if job.Cursor == "" {
+		if err := w.complete(ctx, &job); err != nil {
+			return job, err
+		}
+	}
+	return job, nil
+}
+
+// complete joins the parts of the job into the export file and deletes them.
+// Parts are deleted on a best effort basis once the file is stored, so that
+// a failed completion can be retried from the same checkpoint.
+func (w Worker) complete(ctx context.Context, job *db.ExportJob) error {
+	var readers []io.Reader
+	for part := 0; part < job.Parts; part++ {
+		r, err := w.Store.Get(ctx, partKey(*job, part))
+		if err != nil {
+			return fmt.Errorf("failed to read part %d: %w", part, err)
+		}
+		defer r.Close()
+		readers = append(readers, r)
+	}
+
+	key := fileKey(*job)
+	if err := w.Store.Put(ctx, key, io.MultiReader(readers...)); err != nil {
+		return fmt.Errorf("failed to store export file: %w", err)
+	}
+
+	for part := 0; part < job.Parts; part++ {
+		if err := w.Store.Delete(ctx, partKey(*job, part)); err != nil {
+			log.Printf("failed to delete part %d of export %s: %v", part, job.ID, err)
+		}
+	}
+
+	job.Status = db.ExportCompleted
+	job.File = key
+	job.Cursor = ""
+	return nil
+}
@@ -0,0 +1,133 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+
+	"transactions/internal/db"
+	"transactions/internal/store"
+)
+
+// pager serves pages of transactions, the cursor being the index of the next page.
+type pager struct {
+	pages   [][]db.Transaction
+	queries int
+	err     error
+}
+
+func (p *pager) Query(ctx context.Context, req db.UserListRequest) (db.ListResponse, error) {
+	p.queries++
+	if p.err != nil {
+		return db.ListResponse{}, p.err
+	}
+	i := 0
+	if req.After != "" {
+		i, _ = strconv.Atoi(req.After)
+	}
+	resp := db.ListResponse{Items: p.pages[i]}
+	if i+1 < len(p.pages) {
+		resp.Cursor = strconv.Itoa(i + 1)
+	}
+	return resp, nil
+}
+
+func newJob(format Format) db.ExportJob {
+	job := db.ExportJob{UserID: "john", Format: string(format), From: "2023"}
+	job.SetDefaults()
+	return job
+}
+
+func TestWorker_Step(t *testing.T) {
+	ctx := context.Background()
+	p := &pager{pages: [][]db.Transaction{
+		{{UserID: "john", ID: "1"}, {UserID: "john", ID: "2"}},
+		{{UserID: "john", ID: "3"}},
+		{{UserID: "john", ID: "4"}},
+	}}
+	s := store.NewDir(t.TempDir())
+	w := Worker{Pager: p, Store: s, ChunkPages: 2}
+
+	job, err := w.Step(ctx, newJob(FormatCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != db.ExportRunning || job.Parts != 1 || job.Count != 3 || job.Cursor != "2" {
+		t.Fatalf("unexpected job after first chunk: %+v", job)
+	}
+
+	job, err = w.Step(ctx, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != db.ExportCompleted || job.Parts != 2 || job.Count != 4 || job.Cursor != "" {
+		t.Fatalf("unexpected job after last chunk: %+v", job)
+	}
+	if !strings.HasSuffix(job.File, "/transactions-john-2023.csv") {
+		t.Errorf("unexpected file %s", job.File)
+	}
+
+	r, err := s.Get(ctx, job.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	if len(lines) != 5 || lines[0] != strings.Join(Columns, ",") || !strings.HasPrefix(lines[4], "john,,4,") {
+		t.Errorf("unexpected export file:\n%s", body)
+	}
+
+	if _, err := s.Get(ctx, partKey(job, 0)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected parts to be deleted, got %v", err)
+	}
+
+	queries := p.queries
+	if job, err = w.Step(ctx, job); err != nil || job.Status != db.ExportCompleted || p.queries != queries {
+		t.Errorf("expected completed job to be left as is, got %+v, %v", job, err)
+	}
+}
+
+func TestWorker_StepEmpty(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewDir(t.TempDir())
+	w := Worker{Pager: &pager{pages: [][]db.Transaction{nil}}, Store: s}
+
+	job, err := w.Step(ctx, newJob(FormatNDJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != db.ExportCompleted || job.Count != 0 {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	r, err := s.Get(ctx, job.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+}
+
+func TestWorker_StepError(t *testing.T) {
+	job := newJob(FormatCSV)
+	w := Worker{Pager: &pager{err: errors.New("throttled")}, Store: store.NewDir(t.TempDir())}
+
+	if _, err := w.Step(context.Background(), job); err == nil {
+		t.Fatal("expected error")
+	}
+
+	for i := 0; i < db.MaxExportAttempts; i++ {
+		if job.IsDone() {
+			t.Fatalf("job failed after %d attempts", i)
+		}
+		job.Fail(errors.New("throttled"))
+	}
+	if job.Status != db.ExportFailed || job.Error != "throttled" {
+		t.Errorf("unexpected job: %+v", job)
+	}
+}
@@ -0,0 +1,171 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"transactions/internal/db"
+)
+
+// LedgerWriter writes transactions as ledger-cli journal entries. Posted
+// transactions are cleared and pending ones pending, failed and voided
+// transactions are left out.
+type LedgerWriter struct {
+	w        *bufio.Writer
+	accounts Accounts
+}
+
+// NewLedgerWriter returns a ledger-cli writer booking transactions to the accounts.
+func NewLedgerWriter(w io.Writer, accounts Accounts) *LedgerWriter {
+	return &LedgerWriter{w: bufio.NewWriter(w), accounts: accounts.withDefaults()}
+}
+
+// Write writes the journal entry of the transaction.
+func (w *LedgerWriter) Write(tr db.Transaction) error {
+	if !booked(tr) {
+		return nil
+	}
+	date, err := bookingDate(tr)
+	if err != nil {
+		return err
+	}
+
+	mark := "*"
+	if tr.Status == db.StatusPending {
+		mark = "!"
+	}
+	fmt.Fprintf(w.w, "%s %s %s\n", date.Format("2006/01/02"), mark, oneLine(payee(tr)))
+	if tr.Counterparty != "" && tr.Description != "" {
+		fmt.Fprintf(w.w, "    ; %s\n", oneLine(tr.Description))
+	}
+	fmt.Fprintf(w.w, "    ; tr_id: %s\n", tr.ID)
+	fmt.Fprintf(w.w, "    ; ts: %s\n", tr.Timestamp)
+	if tr.ExternalID != "" {
+		fmt.Fprintf(w.w, "    ; external_id: %s\n", oneLine(tr.ExternalID))
+	}
+	if len(tr.Tags) > 0 {
+		fmt.Fprintf(w.w, "    ; :%s:\n", strings.Join(journalTags(tr.Tags), ":"))
+	}
+	for _, p := range w.accounts.postings(tr) {
+		fmt.Fprintf(w.w, "    %s  %.2f %s\n", p.Account, p.Amount, w.accounts.Currency)
+	}
+	_, err = w.w.WriteString("\n")
+	return err
+}
+
+// Flush flushes the buffered entries.
+func (w *LedgerWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// BeancountWriter writes transactions as beancount journal entries, starting with
+// the operating currency option and the open directives of the accounts. Posted
+// transactions are complete and pending ones incomplete, failed and voided
+// transactions are left out.
+type BeancountWriter struct {
+	w        *bufio.Writer
+	accounts Accounts
+	header   bool
+}
+
+// NewBeancountWriter returns a beancount writer booking transactions to the accounts.
+func NewBeancountWriter(w io.Writer, accounts Accounts) *BeancountWriter {
+	return &BeancountWriter{w: bufio.NewWriter(w), accounts: accounts.withDefaults()}
+}
+
+// Write writes the header before the first transaction, then the journal entry of the transaction.
+func (w *BeancountWriter) Write(tr db.Transaction) error {
+	if !booked(tr) {
+		return nil
+	}
+	date, err := bookingDate(tr)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader(); err != nil {
+		return err
+	}
+
+	flag := "*"
+	if tr.Status == db.StatusPending {
+		flag = "!"
+	}
+	fmt.Fprintf(w.w, "%s %s %s %s", date.Format(dateLayout), flag, quote(tr.Counterparty), quote(tr.Description))
+	for _, tag := range tr.Tags {
+		fmt.Fprintf(w.w, " #%s", journalTag(tag))
+	}
+	fmt.Fprintf(w.w, "\n  tr_id: %s\n  ts: %s\n", quote(tr.ID), quote(tr.Timestamp))
+	if tr.ExternalID != "" {
+		fmt.Fprintf(w.w, "  external_id: %s\n", quote(tr.ExternalID))
+	}
+	for _, p := range w.accounts.postings(tr) {
+		fmt.Fprintf(w.w, "  %s  %.2f %s\n", p.Account, p.Amount, w.accounts.Currency)
+	}
+	_, err = w.w.WriteString("\n")
+	return err
+}
+
+// WriteHeader writes the operating currency option and opens the accounts
+// of the mapping, unless the header is already written.
+func (w *BeancountWriter) WriteHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+
+	fmt.Fprintf(w.w, "option \"operating_currency\" %s\n\n", quote(w.accounts.Currency))
+	for _, name := range w.accounts.Names() {
+		fmt.Fprintf(w.w, "1970-01-01 open %s\n", name)
+	}
+	_, err := w.w.WriteString("\n")
+	return err
+}
+
+func (w *BeancountWriter) skipHeader() { w.header = true }
+
+// Flush writes the header when no transaction was written and flushes the buffered entries.
+func (w *BeancountWriter) Flush() error {
+	if err := w.WriteHeader(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// payee returns the payee of a transaction in journals: its counterparty,
+// or its description or origin when it has none.
+func payee(tr db.Transaction) string {
+	switch {
+	case tr.Counterparty != "":
+		return tr.Counterparty
+	case tr.Description != "":
+		return tr.Description
+	}
+	return tr.Origin
+}
+
+// quote returns s as a beancount string on a single line.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(oneLine(s)) + `"`
+}
+
+// journalTags returns the tags as journal tags.
+func journalTags(tags []string) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = journalTag(tag)
+	}
+	return names
+}
+
+// journalTag returns the tag with the characters beancount tags cannot hold,
+// which include the separators of ledger-cli tags, replaced with dashes.
+func journalTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_/.", r) {
+			return r
+		}
+		return '-'
+	}, tag)
+}
@@ -0,0 +1,238 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"transactions/internal/db"
+)
+
+var journalAccounts = Accounts{
+	Asset:      "Assets:Bank:Checking",
+	Currency:   "EUR",
+	Categories: map[string]string{"groceries": "Expenses:Groceries", "rent": "Expenses:Rent"},
+	Origins:    map[string]string{"payroll": "Income:Salary"},
+}
+
+var journalTransactions = []db.Transaction{
+	{
+		UserID:        "john",
+		Timestamp:     "2024-01-02T10:00:00Z",
+		ID:            "1",
+		Origin:        "card",
+		OperationType: db.OperationDebit,
+		Amount:        42.5,
+		Status:        db.StatusPosted,
+		Counterparty:  "Corner Shop",
+		Description:   `Weekly "shop"`,
+		Tags:          []string{"food", "home office"},
+		Splits: []db.Split{
+			{Amount: 40, Category: "groceries"},
+			{Amount: 2.5, Category: "household"},
+		},
+	},
+	{
+		UserID:        "john",
+		Timestamp:     "2024-01-03T08:00:00Z",
+		ID:            "2",
+		Origin:        "payroll",
+		OperationType: db.OperationCredit,
+		Amount:        1000,
+		Status:        db.StatusPending,
+		ExternalID:    "PAY-1",
+		BookingDate:   "2024-01-04",
+	},
+	{
+		UserID:        "john",
+		Timestamp:     "2024-01-05T08:00:00Z",
+		ID:            "3",
+		Origin:        "card",
+		OperationType: db.OperationDebit,
+		Amount:        9,
+		Status:        db.StatusVoided,
+	},
+}
+
+func writeAll(t *testing.T, f Format) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, f, journalAccounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range journalTransactions {
+		if err := w.Write(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestLedgerWriter(t *testing.T) {
+	want := `2024/01/02 * Corner Shop
+    ; Weekly "shop"
+    ; tr_id: 1
+    ; ts: 2024-01-02T10:00:00Z
+    ; :food:home-office:
+    Expenses:Groceries  40.00 EUR
+    Expenses:Uncategorized  2.50 EUR
+    Assets:Bank:Checking  -42.50 EUR
+
+2024/01/04 ! payroll
+    ; tr_id: 2
+    ; ts: 2024-01-03T08:00:00Z
+    ; external_id: PAY-1
+    Income:Salary  -1000.00 EUR
+    Assets:Bank:Checking  1000.00 EUR
+
+`
+	if got := writeAll(t, FormatLedger); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestBeancountWriter(t *testing.T) {
+	want := `option "operating_currency" "EUR"
+
+1970-01-01 open Assets:Bank:Checking
+1970-01-01 open Expenses:Groceries
+1970-01-01 open Expenses:Rent
+1970-01-01 open Expenses:Uncategorized
+1970-01-01 open Income:Salary
+1970-01-01 open Income:Uncategorized
+
+2024-01-02 * "Corner Shop" "Weekly \"shop\"" #food #home-office
+  tr_id: "1"
+  ts: "2024-01-02T10:00:00Z"
+  Expenses:Groceries  40.00 EUR
+  Expenses:Uncategorized  2.50 EUR
+  Assets:Bank:Checking  -42.50 EUR
+
+2024-01-04 ! "" ""
+  tr_id: "2"
+  ts: "2024-01-03T08:00:00Z"
+  external_id: "PAY-1"
+  Income:Salary  -1000.00 EUR
+  Assets:Bank:Checking  1000.00 EUR
+
+`
+	if got := writeAll(t, FormatBeancount); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestIIFWriter(t *testing.T) {
+	want := "!TRNS\tTRNSID\tTRNSTYPE\tDATE\tACCNT\tNAME\tAMOUNT\tDOCNUM\tMEMO\tCLEAR\r\n" +
+		"!SPL\tSPLID\tTRNSTYPE\tDATE\tACCNT\tNAME\tAMOUNT\tDOCNUM\tMEMO\tCLEAR\r\n" +
+		"!ENDTRNS\r\n" +
+		"TRNS\t\tCHECK\t01/02/2024\tAssets:Bank:Checking\tCorner Shop\t-42.50\t\tWeekly 'shop'\tY\r\n" +
+		"SPL\t\tCHECK\t01/02/2024\tExpenses:Groceries\tCorner Shop\t40.00\t\tWeekly 'shop'\tY\r\n" +
+		"SPL\t\tCHECK\t01/02/2024\tExpenses:Uncategorized\tCorner Shop\t2.50\t\tWeekly 'shop'\tY\r\n" +
+		"ENDTRNS\r\n" +
+		"TRNS\t\tDEPOSIT\t01/04/2024\tAssets:Bank:Checking\t\t1000.00\tPAY-1\t\tN\r\n" +
+		"SPL\t\tDEPOSIT\t01/04/2024\tIncome:Salary\t\t-1000.00\tPAY-1\t\tN\r\n" +
+		"ENDTRNS\r\n"
+	if got := writeAll(t, FormatIIF); got != want {
+		t.Errorf("expected:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+func TestAccounts_Postings(t *testing.T) {
+	tr := db.Transaction{
+		OperationType: db.OperationDebit,
+		Amount:        10,
+		Splits: []db.Split{
+			{Amount: 3.333, Category: "groceries"},
+			{Amount: 3.333, Category: "rent"},
+			{Amount: 3.334, Category: "groceries"},
+		},
+	}
+	want := []posting{
+		{"Expenses:Groceries", 3.33},
+		{"Expenses:Rent", 3.33},
+		{"Expenses:Groceries", 3.34},
+		{"Assets:Bank:Checking", -10},
+	}
+	got := journalAccounts.withDefaults().postings(tr)
+	if len(got) != len(want) {
+		t.Fatalf("expected postings %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected posting %v, got %v", want[i], got[i])
+		}
+	}
+}
+
+func TestAccounts_Account(t *testing.T) {
+	a := Accounts{
+		Categories: map[string]string{"travel": "Expenses:Travel"},
+		Origins:    map[string]string{"atm": "Assets:Cash"},
+		Operations: map[string]string{db.OperationRefund: "Income:Refunds"},
+	}.withDefaults()
+
+	tests := []struct {
+		category, origin, op string
+		want                 string
+	}{
+		{"travel", "atm", db.OperationDebit, "Expenses:Travel"},
+		{"rent", "atm", db.OperationDebit, "Assets:Cash"},
+		{"", "web", db.OperationRefund, "Income:Refunds"},
+		{"", "web", db.OperationDebit, "Expenses:Uncategorized"},
+		{"", "web", db.OperationCredit, "Income:Uncategorized"},
+	}
+	for _, tt := range tests {
+		tr := db.Transaction{Origin: tt.origin, OperationType: tt.op}
+		if got := a.Account(tt.category, tr); got != tt.want {
+			t.Errorf("Account(%q, %s, %s) = %s, want %s", tt.category, tt.origin, tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestAccountsFromEnv(t *testing.T) {
+	prev, ok := os.LookupEnv("EXPORT_ACCOUNTS")
+	defer func() {
+		if ok {
+			os.Setenv("EXPORT_ACCOUNTS", prev)
+		} else {
+			os.Unsetenv("EXPORT_ACCOUNTS")
+		}
+	}()
+
+	os.Setenv("EXPORT_ACCOUNTS", `{"asset": "Assets:Savings", "categories": {"rent": "Expenses:Rent"}}`)
+	a, err := AccountsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Asset != "Assets:Savings" || a.Currency != "USD" || a.Categories["rent"] != "Expenses:Rent" {
+		t.Errorf("unexpected accounts %+v", a)
+	}
+
+	os.Setenv("EXPORT_ACCOUNTS", `{"categories": {"rent": "Expenses:Rent  Home"}}`)
+	if _, err := AccountsFromEnv(); !errors.Is(err, ErrInvalidAccounts) {
+		t.Errorf("expected ErrInvalidAccounts, got %v", err)
+	}
+}
+
+func TestNewPartWriter(t *testing.T) {
+	for _, f := range []Format{FormatCSV, FormatBeancount, FormatIIF} {
+		var first, next bytes.Buffer
+		for part, buf := range []*bytes.Buffer{&first, &next} {
+			w, err := newPartWriter(buf, f, DefaultAccounts, part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if first.Len() == 0 || next.Len() != 0 {
+			t.Errorf("%s: expected header in the first part only, got %q and %q", f, first.String(), next.String())
+		}
+	}
+}
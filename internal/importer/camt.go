@@ -0,0 +1,267 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"transactions/internal/db"
+)
+
+// camtNotProvided is the value of references that were not provided.
+const camtNotProvided = "NOTPROVIDED"
+
+// camtAmount is an amount with its currency.
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtDate is a date or a date and time, of which only the date is used.
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// date returns the date in YYYY-MM-DD format.
+func (d camtDate) date() string {
+	if d.Date != "" {
+		return d.Date
+	}
+	if len(d.DateTime) >= len(dateLayout) {
+		return d.DateTime[:len(dateLayout)]
+	}
+	return ""
+}
+
+// camtParty is a party of a transaction. Its name is nested in Pty since version 8.
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+// name returns the name of the party.
+func (p camtParty) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PartyName
+}
+
+// camtAccount is the account of a statement.
+type camtAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+}
+
+// camtBalance is a balance of a statement.
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+// camtTransactionDetails are the details of a transaction of an entry.
+type camtTransactionDetails struct {
+	EndToEndID  string    `xml:"Refs>EndToEndId"`
+	AcctSvcrRef string    `xml:"Refs>AcctSvcrRef"`
+	Debtor      camtParty `xml:"RltdPties>Dbtr"`
+	Creditor    camtParty `xml:"RltdPties>Cdtr"`
+	Remittance  []string  `xml:"RmtInf>Ustrd"`
+}
+
+// camtStatus is the status of an entry, a code nested in Cd since version 8.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+// camtEntry is an entry of a statement.
+type camtEntry struct {
+	Amount      camtAmount               `xml:"Amt"`
+	Indicator   string                   `xml:"CdtDbtInd"`
+	Reversal    bool                     `xml:"RvslInd"`
+	Status      camtStatus               `xml:"Sts"`
+	BookingDate camtDate                 `xml:"BookgDt"`
+	ValueDate   camtDate                 `xml:"ValDt"`
+	AcctSvcrRef string                   `xml:"AcctSvcrRef"`
+	Details     []camtTransactionDetails `xml:"NtryDtls>TxDtls"`
+	Info        string                   `xml:"AddtlNtryInf"`
+}
+
+// CamtParser parses ISO 20022 camt.053 bank to customer statements.
+type CamtParser struct {
+	origin string
+}
+
+// NewCamtParser returns a parser of camt.053 documents. The Ntry elements
+// of their statements get the origin, which is `import` if empty.
+func NewCamtParser(origin string) *CamtParser {
+	if origin == "" {
+		origin = Origin
+	}
+	return &CamtParser{origin: origin}
+}
+
+// Parse parses the entries of the statements of the document.
+func (p *CamtParser) Parse(r io.Reader, userID string) ([]Entry, error) {
+	statements, err := p.ParseStatements(r, userID)
+	return entries(statements), err
+}
+
+// ParseStatements parses the Stmt elements of the document. Entries are
+// numbered by the line of their Ntry element.
+func (p *CamtParser) ParseStatements(r io.Reader, userID string) ([]Statement, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []Statement
+	var stmt *Statement
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read camt.053 document: %w", err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case el.Name.Local == "Stmt":
+				statements = append(statements, Statement{})
+				stmt = &statements[len(statements)-1]
+			case stmt == nil:
+			case el.Name.Local == "Acct":
+				var acct camtAccount
+				if err := dec.DecodeElement(&acct, &el); err != nil {
+					return nil, err
+				}
+				stmt.Account, stmt.Currency = acct.IBAN, acct.Currency
+				if stmt.Account == "" {
+					stmt.Account = acct.Other
+				}
+			case el.Name.Local == "Bal":
+				var bal camtBalance
+				if err := dec.DecodeElement(&bal, &el); err != nil {
+					return nil, err
+				}
+				p.addBalance(stmt, bal)
+			case el.Name.Local == "Ntry":
+				line := 1 + bytes.Count(data[:dec.InputOffset()], []byte("\n"))
+				var ntry camtEntry
+				if err := dec.DecodeElement(&ntry, &el); err != nil {
+					return nil, err
+				}
+				if tr, ok, err := p.transaction(ntry, userID, line); ok {
+					stmt.Entries = append(stmt.Entries, Entry{Line: line, Transaction: tr, Err: err})
+				}
+			}
+		case xml.EndElement:
+			if el.Name.Local == "Stmt" {
+				stmt = nil
+			}
+		}
+	}
+	if len(statements) == 0 {
+		return nil, errors.New("no camt.053 statements found")
+	}
+	return statements, nil
+}
+
+// addBalance sets the opening or closing booked balance of the statement.
+func (p *CamtParser) addBalance(stmt *Statement, bal camtBalance) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(bal.Amount.Value), 64)
+	if err != nil {
+		return
+	}
+	b := &Balance{
+		Amount:   signed(amount, bal.Indicator == "DBIT"),
+		Currency: bal.Amount.Currency,
+		Date:     bal.Date.date(),
+	}
+	switch bal.Code {
+	case "OPBD", "PRCD":
+		if stmt.Opening == nil || bal.Code == "OPBD" {
+			stmt.Opening = b
+		}
+	case "CLBD":
+		stmt.Closing = b
+	}
+}
+
+// transaction maps an entry to a transaction. Entries for information only
+// are skipped, pending entries are imported as pending transactions.
+func (p *CamtParser) transaction(ntry camtEntry, userID string, line int) (db.Transaction, bool, error) {
+	status := ntry.Status.Code
+	if status == "" {
+		status = strings.TrimSpace(ntry.Status.Text)
+	}
+	if status == "INFO" {
+		return db.Transaction{}, false, nil
+	}
+
+	amount, err := strconv.ParseFloat(strings.TrimSpace(ntry.Amount.Value), 64)
+	if err != nil {
+		return db.Transaction{}, true, fmt.Errorf("invalid amount %q", ntry.Amount.Value)
+	}
+	// a reversal indicator reverses the direction of the entry
+	debit := (ntry.Indicator == "DBIT") != ntry.Reversal
+
+	bookingDate, valueDate := ntry.BookingDate.date(), ntry.ValueDate.date()
+	date := bookingDate
+	if date == "" {
+		date = valueDate
+	}
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return db.Transaction{}, true, fmt.Errorf("invalid booking date %q", date)
+	}
+
+	tr := db.Transaction{
+		UserID:        userID,
+		Timestamp:     entryDate(day),
+		Origin:        p.origin,
+		OperationType: operation(signed(amount, debit)),
+		Amount:        math.Abs(amount),
+		Description:   ntry.Info,
+		ExternalID:    ntry.AcctSvcrRef,
+		BookingDate:   bookingDate,
+		ValueDate:     valueDate,
+	}
+	if status == "PDNG" {
+		tr.Status = db.StatusPending
+	}
+
+	if len(ntry.Details) > 0 {
+		details := ntry.Details[0]
+		if debit {
+			tr.Counterparty = details.Creditor.name()
+		} else {
+			tr.Counterparty = details.Debtor.name()
+		}
+		if details.EndToEndID != camtNotProvided {
+			tr.Reference = details.EndToEndID
+		}
+		if tr.ExternalID == "" {
+			tr.ExternalID = details.AcctSvcrRef
+		}
+		if remittance := strings.Join(details.Remittance, " "); remittance != "" {
+			tr.Description = remittance
+		}
+	}
+	return tr, true, nil
+}
@@ -0,0 +1,428 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+
+	"transactions/internal/db"
+)
+
+// Sign conventions of the amount column
+const (
+	// SignNegativeDebit means negative amounts are debits, as on bank accounts.
+	SignNegativeDebit = "negative-debit"
+	// SignPositiveDebit means positive amounts are debits, as on credit cards.
+	SignPositiveDebit = "positive-debit"
+)
+
+// Columns maps the fields of a transaction to the header names of CSV columns.
+// Either the amount column or the debit and credit columns are required.
+// The date and amount columns must be in the header row, the other columns
+// are left empty when missing.
+type Columns struct {
+	Date   string `json:"date"             validate:"required"`
+	Amount string `json:"amount,omitempty" validate:"required_without_all=Debit Credit"`
+	// Debit and Credit are the columns of statements with separate columns for
+	// debits and credits. Their amounts are taken regardless of their sign.
+	Debit        string `json:"debit,omitempty"`
+	Credit       string `json:"credit,omitempty"`
+	Description  string `json:"description,omitempty"`
+	Counterparty string `json:"counterparty,omitempty"`
+	Category     string `json:"category,omitempty"`
+	// Reference is the column of the bank reference, imported as the external ID.
+	Reference string `json:"reference,omitempty"`
+}
+
+// Profile describes the layout of the CSV statements of a bank.
+type Profile struct {
+	Name string `json:"name,omitempty"`
+	// Delimiter is the field delimiter, a comma by default.
+	Delimiter string `json:"delimiter,omitempty" validate:"omitempty,len=1"`
+	// SkipRows is the number of rows before the header row.
+	SkipRows int     `json:"skip_rows,omitempty" validate:"gte=0"`
+	Columns  Columns `json:"columns"`
+	// DateFormat is the Go time layout of the date column, e.g. 02/01/2006.
+	DateFormat string `json:"date_format" validate:"required"`
+	// DecimalSeparator separates the fraction of amounts, a dot by default or a comma.
+	DecimalSeparator string `json:"decimal_separator,omitempty" validate:"omitempty,len=1"`
+	// ThousandsSeparator groups the digits of amounts, ignored when parsing.
+	ThousandsSeparator string `json:"thousands_separator,omitempty" validate:"omitempty,len=1,nefield=DecimalSeparator"`
+	// Sign is the sign convention of the amount column, negative-debit by default.
+	Sign string `json:"sign,omitempty" validate:"omitempty,oneof=negative-debit positive-debit"`
+	// Origin is the origin of the imported transactions, import by default.
+	Origin string `json:"origin,omitempty"`
+}
+
+// Profiles are the built-in profiles by name.
+var Profiles = map[string]Profile{
+	"default": {
+		Name: "default",
+		Columns: Columns{
+			Date:         "date",
+			Amount:       "amount",
+			Description:  "description",
+			Counterparty: "counterparty",
+			Category:     "category",
+			Reference:    "reference",
+		},
+		DateFormat:         "2006-01-02",
+		ThousandsSeparator: ",",
+	},
+	"european": {
+		Name:      "european",
+		Delimiter: ";",
+		Columns: Columns{
+			Date:         "date",
+			Amount:       "amount",
+			Description:  "description",
+			Counterparty: "counterparty",
+			Category:     "category",
+			Reference:    "reference",
+		},
+		DateFormat:         "02.01.2006",
+		DecimalSeparator:   ",",
+		ThousandsSeparator: ".",
+	},
+}
+
+// ErrUnknownProfile is returned for names of profiles that are not built in.
+var ErrUnknownProfile = errors.New("unknown import profile")
+
+// ProfileByName returns a built-in profile, default when the name is empty.
+func ProfileByName(name string) (Profile, error) {
+	if name == "" {
+		name = "default"
+	}
+	p, ok := Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	return p, nil
+}
+
+// LoadProfile returns the built-in profile with the name, or the profile of the JSON file at the path.
+func LoadProfile(nameOrPath string) (Profile, error) {
+	if _, ok := Profiles[nameOrPath]; ok || nameOrPath == "" {
+		return ProfileByName(nameOrPath)
+	}
+
+	f, err := os.Open(nameOrPath)
+	if err != nil {
+		return Profile{}, err
+	}
+	defer f.Close()
+
+	var profile Profile
+	if err := json.NewDecoder(f).Decode(&profile); err != nil {
+		return Profile{}, fmt.Errorf("failed to decode profile: %w", err)
+	}
+	return profile, nil
+}
+
+// Validate validates the profile.
+func (p Profile) Validate() error {
+	if err := validator.New().Struct(&p); err != nil {
+		return err
+	}
+	if p.DecimalSeparator != "" && p.DecimalSeparator != "." && p.DecimalSeparator != "," {
+		return fmt.Errorf("invalid decimal separator %q", p.DecimalSeparator)
+	}
+	return nil
+}
+
+// ParseAmount parses an amount of the profile. Currency symbols, three letter
+// currency codes before or after the amount and spaces are ignored. Amounts in
+// parentheses, with a trailing minus or with a DR debit marker are negative, a CR
+// credit marker is ignored. Other letters, e.g. exponents, are invalid.
+func (p Profile) ParseAmount(s string) (float64, error) {
+	in := s
+	s = strings.TrimSpace(s)
+	negative := false
+	if marker, rest := cutWord(s, false); strings.EqualFold(marker, "DR") {
+		negative, s = true, rest
+	} else if strings.EqualFold(marker, "CR") {
+		s = rest
+	}
+	if code, rest := cutWord(s, false); len(code) == 3 {
+		s = rest
+	}
+	if code, rest := cutWord(s, true); len(code) == 3 {
+		s = rest
+	}
+
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative, s = true, s[1:len(s)-1]
+	}
+	if strings.HasSuffix(s, "-") {
+		negative, s = true, strings.TrimSuffix(s, "-")
+	}
+
+	decimal := p.DecimalSeparator
+	if decimal == "" {
+		decimal = "."
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '-', r == '+':
+			b.WriteRune(r)
+		case string(r) == decimal:
+			b.WriteRune('.')
+		case string(r) == p.ThousandsSeparator:
+		case strings.ContainsRune("  '$€£¥", r):
+			// currency symbols
+		default:
+			return 0, fmt.Errorf("invalid amount %q", in)
+		}
+	}
+
+	amount, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", in)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// cutWord cuts the word of letters at the end of s, or at its start, from the
+// rest of s trimmed of spaces. The word is empty when s does not end or start
+// with letters.
+func cutWord(s string, start bool) (string, string) {
+	notLetter := func(r rune) bool { return !unicode.IsLetter(r) }
+	var word string
+	if start {
+		i := strings.IndexFunc(s, notLetter)
+		if i < 0 {
+			return "", s
+		}
+		word, s = s[:i], s[i:]
+	} else {
+		i := strings.LastIndexFunc(s, notLetter)
+		word, s = s[i+1:], s[:i+1]
+	}
+	return word, strings.TrimSpace(s)
+}
+
+// CSVParser parses CSV statements with a profile.
+type CSVParser struct {
+	profile Profile
+}
+
+// NewCSVParser returns a parser of CSV statements with the profile.
+func NewCSVParser(profile Profile) (*CSVParser, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid import profile: %w", err)
+	}
+	return &CSVParser{profile: profile}, nil
+}
+
+// Parse parses the rows following the header row. Rows that cannot be
+// parsed into a transaction are returned as entries with an error.
+func (p *CSVParser) Parse(r io.Reader, userID string) ([]Entry, error) {
+	lines := newLineReader(r)
+	reader := csv.NewReader(lines)
+	if p.profile.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(p.profile.Delimiter)
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	for i := 0; i < p.profile.SkipRows; i++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, fmt.Errorf("failed to skip row %d: %w", i+1, err)
+		}
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header row: %w", err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range p.profile.requiredColumns() {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("missing column %q in header row", name)
+		}
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				entries = append(entries, Entry{Line: perr.StartLine, Err: err})
+				continue
+			}
+			return nil, err
+		}
+		line := lines.line - newlines(record)
+		if isBlank(record) {
+			continue
+		}
+
+		field := func(column string) string {
+			if i, ok := index[column]; ok && column != "" && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		tr, err := p.profile.transaction(field, userID, line)
+		entries = append(entries, Entry{Line: line, Transaction: tr, Err: err})
+	}
+	return entries, nil
+}
+
+// lineReader reads at most a line per Read call and counts the lines read.
+// A csv.Reader reading it does not buffer past the line it needs, so the line
+// last read is the last line of the record the csv.Reader returned.
+type lineReader struct {
+	r *bufio.Reader
+	// line is the number of the line last read, 1-based.
+	line int
+	// newLine reports whether the next byte starts a line.
+	newLine bool
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r), newLine: true}
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if l.newLine {
+			l.line++
+			l.newLine = false
+		}
+		p[n] = b
+		n++
+		if b == '\n' {
+			l.newLine = true
+			break
+		}
+	}
+	return n, nil
+}
+
+// newlines returns the number of line breaks in the quoted fields of a record.
+func newlines(record []string) int {
+	n := 0
+	for _, field := range record {
+		n += strings.Count(field, "\n")
+	}
+	return n
+}
+
+// requiredColumns returns the names of the date and amount columns.
+func (p Profile) requiredColumns() []string {
+	var names []string
+	for _, name := range []string{
+		p.Columns.Date,
+		p.Columns.Amount,
+		p.Columns.Debit,
+		p.Columns.Credit,
+	} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// transaction maps the fields of a row to a transaction.
+func (p Profile) transaction(field func(string) string, userID string, line int) (db.Transaction, error) {
+	date, err := time.Parse(p.DateFormat, field(p.Columns.Date))
+	if err != nil {
+		return db.Transaction{}, fmt.Errorf("invalid date %q: %w", field(p.Columns.Date), err)
+	}
+
+	amount, err := p.signedAmount(field)
+	if err != nil {
+		return db.Transaction{}, err
+	}
+	operation := db.OperationCredit
+	if amount < 0 {
+		operation = db.OperationDebit
+	}
+
+	origin := p.Origin
+	if origin == "" {
+		origin = Origin
+	}
+
+	tr := db.Transaction{
+		UserID:        userID,
+		Timestamp:     entryDate(date),
+		Origin:        origin,
+		OperationType: operation,
+		Amount:        math.Abs(amount),
+		Description:   field(p.Columns.Description),
+		Counterparty:  field(p.Columns.Counterparty),
+		Category:      field(p.Columns.Category),
+		ExternalID:    field(p.Columns.Reference),
+	}
+	return tr, nil
+}
+
+// signedAmount returns the amount of a row, negative for debits.
+func (p Profile) signedAmount(field func(string) string) (float64, error) {
+	if p.Columns.Amount != "" && field(p.Columns.Amount) != "" {
+		amount, err := p.ParseAmount(field(p.Columns.Amount))
+		if err != nil {
+			return 0, err
+		}
+		if p.Sign == SignPositiveDebit {
+			amount = -amount
+		}
+		return amount, nil
+	}
+
+	if debit := field(p.Columns.Debit); debit != "" {
+		amount, err := p.ParseAmount(debit)
+		return -math.Abs(amount), err
+	}
+	if credit := field(p.Columns.Credit); credit != "" {
+		amount, err := p.ParseAmount(credit)
+		return math.Abs(amount), err
+	}
+	return 0, errors.New("missing amount")
+}
+
+// isBlank reports whether all fields of the record are blank.
+func isBlank(record []string) bool {
+	for _, f := range record {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"transactions/internal/db"
+)
+
+func TestProfile_ParseAmount(t *testing.T) {
+	us := Profiles["default"]
+	eu := Profiles["european"]
+	tests := []struct {
+		profile Profile
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{us, "12.50", 12.5, false},
+		{us, "-1,234.56", -1234.56, false},
+		{Profile{ThousandsSeparator: ","}, "-1,234.56", -1234.56, false},
+		{us, "$ 12.50", 12.5, false},
+		{us, "(12.50)", -12.5, false},
+		{us, "12.50-", -12.5, false},
+		{eu, "-1.234,56 EUR", -1234.56, false},
+		{eu, "0,99", 0.99, false},
+		{us, "USD 12.50", 12.5, false},
+		{us, "100.00 DR", -100, false},
+		{us, "100.00DR", -100, false},
+		{us, "100.00 CR", 100, false},
+		{us, "100.00 cr", 100, false},
+		{us, "1E5", 0, true},
+		{us, "1e5", 0, true},
+		{us, "100.00 XDR DR", -100, false},
+		{us, "12 EURO", 0, true},
+		{us, "12.5x", 0, true},
+		{us, "", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.profile.ParseAmount(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAmount(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProfile_Validate(t *testing.T) {
+	if err := Profiles["default"].Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Profiles["european"].Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	p := Profiles["default"]
+	p.Columns.Amount = ""
+	if err := p.Validate(); err == nil {
+		t.Errorf("expected error without amount columns")
+	}
+	p.Columns.Debit = "out"
+	if err := p.Validate(); err != nil {
+		t.Errorf("unexpected error with debit column: %v", err)
+	}
+
+	p = Profiles["default"]
+	p.DecimalSeparator = ","
+	p.ThousandsSeparator = ","
+	if err := p.Validate(); err == nil {
+		t.Errorf("expected error with the same decimal and thousands separators")
+	}
+}
+
+func TestCSVParser_Parse(t *testing.T) {
+	const statement = `Account statement
+date;description;counterparty;amount;reference
+02.01.2024;Coffee;"Blue Bottle; Berlin";-3,50;A1
+02.01.2024;Salary;ACME;1.500,00;A2
+
+31.02.2024;Bad date;;1,00;A3
+03.01.2024;No amount;;;A4
+`
+	profile := Profiles["european"]
+	profile.SkipRows = 1
+	p, err := NewCSVParser(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := p.Parse(strings.NewReader(statement), "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d: %+v", len(entries), entries)
+	}
+
+	coffee := entries[0]
+	if coffee.Err != nil || coffee.Line != 3 {
+		t.Fatalf("unexpected entry %+v", coffee)
+	}
+	want := db.Transaction{
+		UserID:        "john",
+		Timestamp:     "2024-01-02T00:00:00Z",
+		Origin:        Origin,
+		OperationType: db.OperationDebit,
+		Amount:        3.5,
+		Description:   "Coffee",
+		Counterparty:  "Blue Bottle; Berlin",
+		ExternalID:    "A1",
+	}
+	got := coffee.Transaction
+	if got.UserID != want.UserID || got.Timestamp != want.Timestamp || got.Origin != want.Origin ||
+		got.OperationType != want.OperationType || got.Amount != want.Amount ||
+		got.Description != want.Description || got.Counterparty != want.Counterparty ||
+		got.ExternalID != want.ExternalID {
+		t.Errorf("unexpected transaction %+v, want %+v", got, want)
+	}
+
+	salary := entries[1].Transaction
+	if salary.OperationType != db.OperationCredit || salary.Amount != 1500 {
+		t.Errorf("unexpected transaction %+v", salary)
+	}
+
+	if entries[2].Err == nil || entries[2].Line != 6 {
+		t.Errorf("expected invalid date error on line 6, got %+v", entries[2])
+	}
+	if entries[3].Err == nil || entries[3].Line != 7 {
+		t.Errorf("expected missing amount error on line 7, got %+v", entries[3])
+	}
+}
+
+func TestCSVParser_ParseLines(t *testing.T) {
+	const statement = "date,description,amount\r\n\r\n" +
+		"2024-01-02,\"Two\r\nlines\",1.00\r\n" +
+		"2024-01-03,\"Three\n\nlines\",2.00\n" +
+		"\n" +
+		"2024-01-04,Last,3.00"
+	p, err := NewCSVParser(Profiles["default"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := p.Parse(strings.NewReader(statement), "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{3, 5, 9}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(want), len(entries), entries)
+	}
+	for i, line := range want {
+		if entries[i].Err != nil || entries[i].Line != line {
+			t.Errorf("expected entry %d on line %d, got %+v", i, line, entries[i])
+		}
+	}
+}
+
+func TestCSVParser_ParseDebitCreditColumns(t *testing.T) {
+	const statement = "Date,Details,Paid out,Paid in\n01/02/2024,Rent,800.00,\n01/02/2024,Refund,,20.00\n"
+	p, err := NewCSVParser(Profile{
+		Columns:    Columns{Date: "Date", Description: "Details", Debit: "Paid out", Credit: "Paid in"},
+		DateFormat: "02/01/2006",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := p.Parse(strings.NewReader(statement), "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if tr := entries[0].Transaction; tr.OperationType != db.OperationDebit || tr.Amount != 800 ||
+		tr.Timestamp != "2024-02-01T00:00:00Z" {
+		t.Errorf("unexpected transaction %+v", tr)
+	}
+	if tr := entries[1].Transaction; tr.OperationType != db.OperationCredit || tr.Amount != 20 {
+		t.Errorf("unexpected transaction %+v", tr)
+	}
+}
+
+func TestCSVParser_ParseMissingColumn(t *testing.T) {
+	p, err := NewCSVParser(Profiles["default"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Parse(strings.NewReader("date,value\n2024-01-01,1\n"), "john"); err == nil {
+		t.Errorf("expected error for missing columns")
+	}
+}
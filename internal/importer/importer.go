@@ -0,0 +1,294 @@
+// Package importer imports bank statements as transactions.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"transactions/internal/db"
+)
+
+// DefaultBatchSize is the number of transactions written per batch.
+const DefaultBatchSize = 100
+
+// maxRekeys is how many times a transaction is given another key when a
+// transaction with its key exists, see entryKeys.
+const maxRekeys = 3
+
+// Origin is the default origin of imported transactions.
+const Origin = "import"
+
+// ErrUnknownFormat is returned for statement formats without a parser.
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// Entry is a transaction parsed from a statement, or the error parsing it.
+type Entry struct {
+	// Line is the line of the statement the transaction starts at.
+	Line        int
+	Transaction db.Transaction
+	Err         error
+}
+
+// Parser parses a statement into entries of transactions of a user.
+type Parser interface {
+	Parse(r io.Reader, userID string) ([]Entry, error)
+}
+
+// NewParser returns the parser of a statement format, csv when empty.
+// The profile describes CSV statements, only its origin applies to other formats.
+func NewParser(format string, profile Profile) (Parser, error) {
+	switch strings.ToLower(format) {
+	case "", "csv":
+		return NewCSVParser(profile)
+	case "ofx", "qfx":
+		return NewOFXParser(profile.Origin), nil
+	case "qif":
+		return NewQIFParser(profile.Origin), nil
+	case "camt", "camt.053":
+		return NewCamtParser(profile.Origin), nil
+	case "mt940":
+		return NewMT940Parser(profile.Origin), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+// RowError is the error of a statement entry that was not imported.
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Duplicate is a statement entry detected as a duplicate of an existing transaction.
+type Duplicate struct {
+	Line        int              `json:"line"`
+	DuplicateOf db.TransactionPK `json:"duplicate_of"`
+	// Rejected reports whether the entry was rejected rather than imported.
+	Rejected bool `json:"rejected"`
+}
+
+// Report reports the result of an import.
+type Report struct {
+	Rows     int        `json:"rows"`
+	Imported int        `json:"imported"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors,omitempty"`
+	// Duplicates are the entries detected as duplicates of existing
+	// transactions, rejected or flagged depending on the duplicate mode.
+	Duplicates []Duplicate `json:"duplicates,omitempty"`
+	// Balances are the balance checks of statements reporting balances.
+	Balances []BalanceCheck `json:"balances,omitempty"`
+}
+
+// fail records the error of an entry.
+func (r *Report) fail(line int, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, RowError{Line: line, Error: err.Error()})
+}
+
+// Writer writes batches of transactions, see db.Client.CreateBatch.
+type Writer interface {
+	CreateBatch(ctx context.Context, trs []db.Transaction) ([]error, error)
+}
+
+// Importer validates the entries of statements and writes the valid ones in batches.
+type Importer struct {
+	Writer Writer
+	// BatchSize is the number of transactions per batch, DefaultBatchSize if not set.
+	BatchSize int
+	// DryRun only validates the entries without writing them.
+	DryRun bool
+}
+
+// Import imports the entries and reports the entries that failed.
+func (im Importer) Import(ctx context.Context, entries []Entry) (Report, error) {
+	report := Report{Rows: len(entries)}
+
+	size := im.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	keys := entryKeys{}
+	batch := make([]db.Transaction, 0, size)
+	lines := make([]int, 0, size)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if !im.DryRun {
+			errs, err := im.write(ctx, batch, keys)
+			if err != nil {
+				return fmt.Errorf("failed to write batch: %w", err)
+			}
+			for i, err := range errs {
+				var dup *db.DuplicateError
+				switch {
+				case errors.As(err, &dup):
+					report.Duplicates = append(report.Duplicates, Duplicate{
+						Line:        lines[i],
+						DuplicateOf: dup.Of,
+						Rejected:    true,
+					})
+				case err == nil && batch[i].DuplicateOf != nil:
+					report.Duplicates = append(report.Duplicates, Duplicate{
+						Line:        lines[i],
+						DuplicateOf: *batch[i].DuplicateOf,
+					})
+				}
+				if err != nil {
+					report.fail(lines[i], err)
+					report.Imported--
+				}
+			}
+		}
+		report.Imported += len(batch)
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	for _, e := range entries {
+		if e.Err != nil {
+			report.fail(e.Line, e.Err)
+			continue
+		}
+		tr := e.Transaction
+		tr.SetDefaults()
+		if err := tr.Validate(); err != nil {
+			report.fail(e.Line, err)
+			continue
+		}
+		var err error
+		if tr.Timestamp, err = keys.timestamp(tr); err != nil {
+			report.fail(e.Line, err)
+			continue
+		}
+
+		batch = append(batch, tr)
+		lines = append(lines, e.Line)
+		if len(batch) == size {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// write writes a batch, giving the transactions failing as a transaction with
+// their key exists other keys up to maxRekeys times.
+func (im Importer) write(ctx context.Context, batch []db.Transaction, keys entryKeys) ([]error, error) {
+	errs := make([]error, len(batch))
+	pending := make([]int, len(batch))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		trs := make([]db.Transaction, len(pending))
+		for j, i := range pending {
+			trs[j] = batch[i]
+		}
+		written, err := im.Writer.CreateBatch(ctx, trs)
+		if err != nil {
+			return nil, err
+		}
+
+		var rekeyed []int
+		for j, i := range pending {
+			batch[i] = trs[j]
+			errs[i] = written[j]
+			if !errors.Is(written[j], db.ErrTransactionExists) || attempt == maxRekeys {
+				continue
+			}
+			if batch[i].Timestamp, err = keys.timestamp(batch[i]); err != nil {
+				return nil, err
+			}
+			batch[i].DuplicateOf = nil
+			rekeyed = append(rekeyed, i)
+		}
+		pending = rekeyed
+	}
+	return errs, nil
+}
+
+// entryDate returns the timestamp of the date of a statement entry. Entries are
+// keyed within their day on import, see entryKeys.
+func entryDate(date time.Time) string {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).Format(db.TimestampLayout)
+}
+
+// microsPerDay is the number of microseconds of a day, the precision of timestamps.
+const microsPerDay = uint64(24 * time.Hour / time.Microsecond)
+
+// entryKeys assigns the timestamps, and so the keys, of imported transactions:
+// the date of the entry plus an offset within the day hashed from its content and
+// its ordinal among the identical entries of the import. Entries of other
+// statements of the same day, such as the statements of other accounts or of
+// overlapping periods, get other keys, while an entry imported again gets its key
+// again and is left to the duplicate detection.
+type entryKeys map[string]int
+
+// timestamp returns the next timestamp of the transaction of an entry, whose
+// timestamp is the date of the entry.
+func (k entryKeys) timestamp(tr db.Transaction) (string, error) {
+	if len(tr.Timestamp) < len(dateLayout) {
+		return "", fmt.Errorf("invalid entry date %q", tr.Timestamp)
+	}
+	day, err := time.Parse(dateLayout, tr.Timestamp[:len(dateLayout)])
+	if err != nil {
+		return "", fmt.Errorf("invalid entry date %q", tr.Timestamp)
+	}
+
+	content := strings.Join([]string{
+		tr.UserID,
+		day.Format(dateLayout),
+		tr.OperationType,
+		strconv.FormatFloat(tr.Amount, 'f', -1, 64),
+		tr.ExternalID,
+		tr.Counterparty,
+		tr.Description,
+	}, "\x00")
+	ordinal := k[content]
+	k[content]++
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%d", content, ordinal)
+	offset := time.Duration(h.Sum64()%microsPerDay) * time.Microsecond
+	return day.Add(offset).Format(db.TimestampLayout), nil
+}
+
+// Request is a request to import a statement of a user.
+type Request struct {
+	// Format is the format of the statement, csv by default.
+	Format string `json:"format,omitempty"`
+	// ProfileName is the name of a built-in profile of CSV statements,
+	// used when no profile is given.
+	ProfileName string   `json:"profile_name,omitempty"`
+	Profile     *Profile `json:"profile,omitempty"`
+	// Data is the content of the statement.
+	Data   string `json:"data"`
+	DryRun bool   `json:"dry_run,omitempty"`
+}
+
+// Parser returns the parser of the statement of the request.
+func (r Request) Parser() (Parser, error) {
+	var profile Profile
+	if r.Profile != nil {
+		profile = *r.Profile
+	} else {
+		var err error
+		if profile, err = ProfileByName(r.ProfileName); err != nil {
+			return nil, err
+		}
+	}
+	return NewParser(r.Format, profile)
+}
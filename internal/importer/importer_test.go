@@ -0,0 +1,265 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"transactions/internal/db"
+)
+
+// writer records the batches written, failing and flagging as duplicates the transactions by description.
+type writer struct {
+	batches [][]db.Transaction
+	fail    map[string]error
+	flag    map[string]db.TransactionPK
+}
+
+func (w *writer) CreateBatch(ctx context.Context, trs []db.Transaction) ([]error, error) {
+	w.batches = append(w.batches, append([]db.Transaction(nil), trs...))
+	errs := make([]error, len(trs))
+	for i, tr := range trs {
+		errs[i] = w.fail[tr.Description]
+		if pk, ok := w.flag[tr.Description]; ok {
+			trs[i].DuplicateOf = &pk
+		}
+	}
+	return errs, nil
+}
+
+func entry(line int, description string) Entry {
+	return Entry{Line: line, Transaction: db.Transaction{
+		UserID:        "john",
+		Timestamp:     "2024-01-01T00:00:00Z",
+		Origin:        Origin,
+		OperationType: db.OperationDebit,
+		Amount:        1,
+		Description:   description,
+	}}
+}
+
+func TestImporter_Import(t *testing.T) {
+	w := &writer{fail: map[string]error{"unknown category": db.ErrUnknownCategory}}
+	im := Importer{Writer: w, BatchSize: 2}
+
+	invalid := entry(4, "no user")
+	invalid.Transaction.UserID = ""
+	entries := []Entry{
+		entry(2, "coffee"),
+		{Line: 3, Err: errors.New("invalid date")},
+		invalid,
+		entry(5, "unknown category"),
+		entry(6, "rent"),
+	}
+
+	report, err := im.Import(context.Background(), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 5 || report.Imported != 2 || report.Failed != 3 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	var lines []int
+	for _, e := range report.Errors {
+		lines = append(lines, e.Line)
+	}
+	if len(lines) != 3 || lines[0] != 3 || lines[1] != 4 || lines[2] != 5 {
+		t.Errorf("unexpected errors %+v", report.Errors)
+	}
+	if len(w.batches) != 2 || len(w.batches[0]) != 2 || len(w.batches[1]) != 1 {
+		t.Errorf("unexpected batches %+v", w.batches)
+	}
+}
+
+func TestImporter_ImportDryRun(t *testing.T) {
+	w := &writer{}
+	im := Importer{Writer: w, DryRun: true}
+
+	report, err := im.Import(context.Background(), []Entry{entry(2, "coffee"), entry(3, "rent")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 2 || len(w.batches) != 0 {
+		t.Errorf("unexpected dry run report %+v, batches %d", report, len(w.batches))
+	}
+}
+
+func TestImporter_ImportDuplicates(t *testing.T) {
+	coffee := db.TransactionPK{UserID: "john", Timestamp: "2023-12-31T00:00:00Z"}
+	rent := db.TransactionPK{UserID: "john", Timestamp: "2023-12-01T00:00:00Z"}
+	w := &writer{
+		fail: map[string]error{"rent": &db.DuplicateError{Of: rent}},
+		flag: map[string]db.TransactionPK{"coffee": coffee},
+	}
+	im := Importer{Writer: w}
+
+	report, err := im.Import(context.Background(), []Entry{entry(2, "coffee"), entry(3, "rent"), entry(4, "lunch")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 2 || report.Failed != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	want := []Duplicate{
+		{Line: 2, DuplicateOf: coffee},
+		{Line: 3, DuplicateOf: rent, Rejected: true},
+	}
+	if len(report.Duplicates) != len(want) {
+		t.Fatalf("expected duplicates %+v, got %+v", want, report.Duplicates)
+	}
+	for i := range want {
+		if report.Duplicates[i] != want[i] {
+			t.Errorf("expected duplicate %+v, got %+v", want[i], report.Duplicates[i])
+		}
+	}
+}
+
+// store writes transactions as the conditional puts of db.Client.CreateBatch,
+// failing transactions with the primary key of a stored one as duplicates.
+type store map[db.TransactionPK]db.Transaction
+
+// CreateBatch rejects the duplicates of stored transactions, as with the reject
+// duplicate mode, and fails the transactions with the key of another one.
+func (s store) CreateBatch(ctx context.Context, trs []db.Transaction) ([]error, error) {
+	errs := make([]error, len(trs))
+	for i, tr := range trs {
+		if existing, ok := s[tr.PK()]; ok {
+			errs[i] = fmt.Errorf("%w: %s", db.ErrTransactionExists, tr.Timestamp)
+			if existing.Amount == tr.Amount && existing.OperationType == tr.OperationType &&
+				existing.Origin == tr.Origin && existing.ExternalID == tr.ExternalID {
+				errs[i] = &db.DuplicateError{Of: tr.PK()}
+			}
+			continue
+		}
+		s[tr.PK()] = tr
+	}
+	return errs, nil
+}
+
+// find returns the stored transaction with the description.
+func (s store) find(description string) db.Transaction {
+	for _, tr := range s {
+		if tr.Description == description {
+			return tr
+		}
+	}
+	return db.Transaction{}
+}
+
+func TestImporter_ImportTwice(t *testing.T) {
+	const statement = "Date,Details,Paid out,Paid in\n01/02/2024,Rent,800.00,\n01/02/2024,Refund,,20.00\n"
+	p, err := NewCSVParser(Profile{
+		Columns:    Columns{Date: "Date", Description: "Details", Debit: "Paid out", Credit: "Paid in"},
+		DateFormat: "02/01/2006",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := store{}
+	im := Importer{Writer: s}
+	entries, err := p.Parse(strings.NewReader(statement), "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report, err := im.Import(context.Background(), entries); err != nil || report.Imported != 2 {
+		t.Fatalf("unexpected first import %+v, %v", report, err)
+	}
+	rent := s.find("Rent").PK()
+	refunded := s[rent]
+	refunded.RefundedAmount = 100
+	s[rent] = refunded
+
+	entries, err = p.Parse(strings.NewReader(statement), "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := im.Import(context.Background(), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 0 || report.Failed != 2 || len(report.Duplicates) != 2 {
+		t.Errorf("expected the entries of the statement imported again to be duplicates, got %+v", report)
+	}
+	if report.Duplicates[0] != (Duplicate{Line: 2, DuplicateOf: rent, Rejected: true}) {
+		t.Errorf("unexpected duplicate %+v", report.Duplicates[0])
+	}
+	if s[rent].RefundedAmount != 100 {
+		t.Errorf("expected the imported transaction not to be overwritten, got %+v", s[rent])
+	}
+}
+
+func TestImporter_ImportOtherStatement(t *testing.T) {
+	// the statements of two accounts with entries on the same lines and days
+	const checking = "Date,Details,Paid out,Paid in\n01/02/2024,Rent,800.00,\n"
+	const savings = "Date,Details,Paid out,Paid in\n01/02/2024,Interest,,1.50\n"
+	p, err := NewCSVParser(Profile{
+		Columns:    Columns{Date: "Date", Description: "Details", Debit: "Paid out", Credit: "Paid in"},
+		DateFormat: "02/01/2006",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := store{}
+	im := Importer{Writer: s}
+	for _, statement := range []string{checking, savings} {
+		entries, err := p.Parse(strings.NewReader(statement), "john")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report, err := im.Import(context.Background(), entries); err != nil || report.Imported != 1 {
+			t.Fatalf("unexpected import %+v, %v", report, err)
+		}
+	}
+	if len(s) != 2 || s.find("Rent").Amount != 800 || s.find("Interest").Amount != 1.5 {
+		t.Errorf("expected the transactions of both statements, got %+v", s)
+	}
+}
+
+func TestImporter_ImportExistingKey(t *testing.T) {
+	e := entry(2, "coffee")
+	ts, err := entryKeys{}.timestamp(e.Transaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another transaction with the key of the entry
+	other := db.Transaction{UserID: "john", Timestamp: ts, Origin: "web", Amount: 99}
+	s := store{other.PK(): other}
+
+	report, err := Importer{Writer: s}.Import(context.Background(), []Entry{e})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 1 || len(report.Duplicates) != 0 {
+		t.Errorf("expected the entry to be imported with another key, got %+v", report)
+	}
+	if len(s) != 2 || s[other.PK()].Amount != 99 || s.find("coffee").Timestamp == ts {
+		t.Errorf("unexpected transactions %+v", s)
+	}
+}
+
+func TestEntryKeys(t *testing.T) {
+	keys := entryKeys{}
+	coffee := entry(2, "coffee").Transaction
+	first, _ := keys.timestamp(coffee)
+	second, _ := keys.timestamp(coffee)
+	lunch, _ := keys.timestamp(entry(3, "lunch").Transaction)
+	if first == second || first == lunch || second == lunch {
+		t.Errorf("expected distinct keys, got %s, %s and %s", first, second, lunch)
+	}
+	for _, ts := range []string{first, second, lunch} {
+		if !strings.HasPrefix(ts, "2024-01-01T") {
+			t.Errorf("expected a key on the day of the entry, got %s", ts)
+		}
+	}
+
+	if again, _ := (entryKeys{}).timestamp(coffee); again != first {
+		t.Errorf("expected the entry imported again to get its key %s, got %s", first, again)
+	}
+	if _, err := keys.timestamp(db.Transaction{Timestamp: "soon"}); err == nil {
+		t.Errorf("expected an error for an invalid date")
+	}
+}
@@ -0,0 +1,289 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"transactions/internal/db"
+)
+
+// mt940NoReference is the value of references that were not provided.
+const mt940NoReference = "NONREF"
+
+var (
+	// mt940Balance matches balance fields: mark, date, currency and amount, e.g. C240101EUR1234,56.
+	mt940Balance = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})([\d,]+)$`)
+	// mt940Line matches statement line fields: value date, optional entry date, mark,
+	// optional funds code, amount, transaction type, customer and bank references,
+	// e.g. 2401020102D3,50NTRFNONREF//B4A02.
+	mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[CD])[A-Z]?([\d,]+)([A-Z][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+	// mt940Subfield matches the ?NN subfields of structured information fields.
+	mt940Subfield = regexp.MustCompile(`\?(\d{2})`)
+	// mt940Code matches the /CODE/ tags of structured information fields.
+	mt940Code = regexp.MustCompile(`/([A-Z]{2,4})/`)
+)
+
+// mt940Field is a field of a message, its content possibly spanning lines.
+type mt940Field struct {
+	line    int
+	tag     string
+	content string
+}
+
+// MT940Parser parses SWIFT MT940 customer statement messages.
+type MT940Parser struct {
+	origin string
+}
+
+// NewMT940Parser returns a parser of MT940 messages. The :61: statement
+// lines become transactions of the origin, which is `import` if empty.
+func NewMT940Parser(origin string) *MT940Parser {
+	if origin == "" {
+		origin = Origin
+	}
+	return &MT940Parser{origin: origin}
+}
+
+// Parse parses the entries of the statements of the document.
+func (p *MT940Parser) Parse(r io.Reader, userID string) ([]Entry, error) {
+	statements, err := p.ParseStatements(r, userID)
+	return entries(statements), err
+}
+
+// mt940Fields splits a document into fields. SWIFT block headers and
+// trailers around the text block are skipped.
+func mt940Fields(r io.Reader) ([]mt940Field, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []mt940Field
+	scanner := bufio.NewScanner(strings.NewReader(toUTF8(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r ")
+		if i := strings.Index(text, "{4:"); i >= 0 {
+			text = text[i+3:]
+		}
+		switch {
+		case text == "" || text == "-" || strings.HasPrefix(text, "-}") || strings.HasPrefix(text, "{"):
+			continue
+		case strings.HasPrefix(text, ":") && strings.Index(text[1:], ":") > 0:
+			end := strings.Index(text[1:], ":") + 1
+			fields = append(fields, mt940Field{line: line, tag: text[1:end], content: text[end+1:]})
+		case len(fields) > 0:
+			fields[len(fields)-1].content += "\n" + text
+		}
+	}
+	return fields, scanner.Err()
+}
+
+// ParseStatements parses the statements of the document, each starting
+// with a :20: field. Information :86: fields describe the preceding
+// :61: statement line.
+func (p *MT940Parser) ParseStatements(r io.Reader, userID string) ([]Statement, error) {
+	fields, err := mt940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []Statement
+	var stmt *Statement
+	for _, f := range fields {
+		if f.tag == "20" {
+			statements = append(statements, Statement{})
+			stmt = &statements[len(statements)-1]
+			continue
+		}
+		if stmt == nil {
+			return nil, fmt.Errorf("line %d: field :%s: before the :20: field", f.line, f.tag)
+		}
+
+		switch f.tag {
+		case "25":
+			stmt.Account = f.content
+		case "60F", "60M":
+			if stmt.Opening, err = parseMT940Balance(f.content); err != nil {
+				return nil, fmt.Errorf("line %d: %w", f.line, err)
+			}
+			stmt.Currency = stmt.Opening.Currency
+		case "62F", "62M":
+			if stmt.Closing, err = parseMT940Balance(f.content); err != nil {
+				return nil, fmt.Errorf("line %d: %w", f.line, err)
+			}
+		case "61":
+			tr, err := p.transaction(f.content, userID, f.line)
+			stmt.Entries = append(stmt.Entries, Entry{Line: f.line, Transaction: tr, Err: err})
+		case "86":
+			if n := len(stmt.Entries); n > 0 && stmt.Entries[n-1].Err == nil {
+				addMT940Information(&stmt.Entries[n-1].Transaction, f.content)
+			}
+		}
+	}
+	if len(statements) == 0 {
+		return nil, errors.New("no MT940 statements found")
+	}
+	return statements, nil
+}
+
+// parseMT940Amount parses an amount with a decimal comma.
+func parseMT940Amount(s string) (float64, error) {
+	amount, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+// parseMT940Date parses a YYMMDD date.
+func parseMT940Date(s string) (time.Time, error) {
+	date, err := time.Parse("060102", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return date, nil
+}
+
+// parseMT940Balance parses a balance field.
+func parseMT940Balance(s string) (*Balance, error) {
+	m := mt940Balance.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, fmt.Errorf("invalid balance %q", s)
+	}
+	date, err := parseMT940Date(m[2])
+	if err != nil {
+		return nil, err
+	}
+	amount, err := parseMT940Amount(m[4])
+	if err != nil {
+		return nil, err
+	}
+	return &Balance{
+		Amount:   signed(amount, m[1] == "D"),
+		Currency: m[3],
+		Date:     date.Format(dateLayout),
+	}, nil
+}
+
+// transaction maps a statement line to a transaction. The supplementary
+// details on the second line of the field are used as the description.
+func (p *MT940Parser) transaction(content, userID string, line int) (db.Transaction, error) {
+	lines := strings.SplitN(content, "\n", 2)
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if m == nil {
+		return db.Transaction{}, fmt.Errorf("invalid statement line %q", lines[0])
+	}
+
+	valueDate, err := parseMT940Date(m[1])
+	if err != nil {
+		return db.Transaction{}, err
+	}
+	bookingDate := valueDate
+	if m[2] != "" {
+		if bookingDate, err = mt940EntryDate(valueDate, m[2]); err != nil {
+			return db.Transaction{}, err
+		}
+	}
+
+	amount, err := parseMT940Amount(m[4])
+	if err != nil {
+		return db.Transaction{}, err
+	}
+	// reversals of credits are debits and reversals of debits are credits
+	debit := m[3] == "D" || m[3] == "RC"
+
+	tr := db.Transaction{
+		UserID:        userID,
+		Timestamp:     entryDate(bookingDate),
+		Origin:        p.origin,
+		OperationType: operation(signed(amount, debit)),
+		Amount:        math.Abs(amount),
+		ExternalID:    strings.TrimSpace(m[7]),
+		BookingDate:   bookingDate.Format(dateLayout),
+		ValueDate:     valueDate.Format(dateLayout),
+	}
+	if ref := strings.TrimSpace(m[6]); ref != mt940NoReference {
+		tr.Reference = ref
+	}
+	if len(lines) > 1 {
+		tr.Description = strings.TrimSpace(lines[1])
+	}
+	return tr, nil
+}
+
+// mt940EntryDate returns the MMDD entry date in the year closest to the value date.
+func mt940EntryDate(valueDate time.Time, mmdd string) (time.Time, error) {
+	date, err := time.Parse("20060102", strconv.Itoa(valueDate.Year())+mmdd)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid entry date %q", mmdd)
+	}
+	switch {
+	case date.Sub(valueDate) > 180*24*time.Hour:
+		date = date.AddDate(-1, 0, 0)
+	case valueDate.Sub(date) > 180*24*time.Hour:
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, nil
+}
+
+// addMT940Information sets the counterparty and description of a transaction
+// from an information field, either structured in ?NN subfields, where ?20 to
+// ?29 and ?60 to ?63 hold the remittance information and ?32 and ?33 the
+// counterparty name, or in /CODE/ tags such as /NAME/ and /REMI/, or free text.
+func addMT940Information(tr *db.Transaction, content string) {
+	content = strings.ReplaceAll(content, "\n", "")
+
+	if locs := mt940Subfield.FindAllStringSubmatchIndex(content, -1); len(locs) > 0 {
+		var name, remittance []string
+		for i, loc := range locs {
+			end := len(content)
+			if i+1 < len(locs) {
+				end = locs[i+1][0]
+			}
+			code, _ := strconv.Atoi(content[loc[2]:loc[3]])
+			value := content[loc[1]:end]
+			switch {
+			case code == 32 || code == 33:
+				// the name is split into subfields of 27 characters
+				name = append(name, value)
+			case code >= 20 && code <= 29, code >= 60 && code <= 63:
+				remittance = append(remittance, strings.TrimSpace(value))
+			}
+		}
+		setInformation(tr, strings.TrimSpace(strings.Join(name, "")), strings.Join(remittance, " "))
+		return
+	}
+
+	if locs := mt940Code.FindAllStringSubmatchIndex(content, -1); len(locs) > 0 {
+		values := map[string]string{}
+		for i, loc := range locs {
+			end := len(content)
+			if i+1 < len(locs) {
+				end = locs[i+1][0]
+			}
+			values[content[loc[2]:loc[3]]] = strings.TrimSpace(content[loc[1]:end])
+		}
+		setInformation(tr, values["NAME"], values["REMI"])
+		return
+	}
+
+	setInformation(tr, "", strings.TrimSpace(content))
+}
+
+// setInformation sets the non empty counterparty and description of a transaction.
+func setInformation(tr *db.Transaction, counterparty, description string) {
+	if counterparty != "" {
+		tr.Counterparty = counterparty
+	}
+	if description != "" {
+		tr.Description = description
+	}
+}
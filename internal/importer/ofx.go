@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"transactions/internal/db"
+)
+
+// ofxDateLayout is the layout of the date part of OFX dates, which may be
+// followed by a time, fractional seconds and a time zone: 20240102120000.000[-5:EST].
+const ofxDateLayout = "20060102"
+
+// ofxToken is a tag or the text following a tag of an OFX document.
+type ofxToken struct {
+	line int
+	// tag is the name of a start tag, or of an end tag prefixed by /.
+	tag  string
+	text string
+}
+
+// ofxTokens splits an OFX document into tags and texts. It reads both
+// the SGML variant, where elements are not closed, and the XML variant.
+// Headers, processing instructions and comments are skipped.
+func ofxTokens(data string) []ofxToken {
+	var tokens []ofxToken
+	line := 1
+	for {
+		start := strings.IndexByte(data, '<')
+		if start < 0 {
+			break
+		}
+		text := strings.TrimSpace(data[:start])
+		if text != "" && len(tokens) > 0 && tokens[len(tokens)-1].text == "" {
+			tokens[len(tokens)-1].text = html.UnescapeString(text)
+		}
+		line += strings.Count(data[:start], "\n")
+
+		end := strings.IndexByte(data[start:], '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.TrimSpace(data[start+1 : start+end])
+		if tag != "" && tag[0] != '?' && tag[0] != '!' {
+			if i := strings.IndexAny(tag, " \t\r\n"); i >= 0 {
+				tag = tag[:i]
+			}
+			tokens = append(tokens, ofxToken{line: line, tag: strings.ToUpper(tag)})
+		}
+		line += strings.Count(data[start:start+end], "\n")
+		data = data[start+end+1:]
+	}
+	return tokens
+}
+
+// toUTF8 decodes data as Latin-1 unless it is valid UTF-8,
+// as statements are often encoded with Windows code pages.
+func toUTF8(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// OFXParser parses OFX and QFX statements.
+type OFXParser struct {
+	origin string
+}
+
+// NewOFXParser returns a parser of OFX and QFX statements. The STMTTRN
+// entries it parses get the origin, which is `import` if empty.
+func NewOFXParser(origin string) *OFXParser {
+	if origin == "" {
+		origin = Origin
+	}
+	return &OFXParser{origin: origin}
+}
+
+// Parse parses the STMTTRN aggregates of the statement. The FITID of
+// a transaction is preserved as its external ID.
+func (p *OFXParser) Parse(r io.Reader, userID string) ([]Entry, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := ofxTokens(toUTF8(data))
+	if len(tokens) == 0 {
+		return nil, errors.New("no OFX elements found")
+	}
+
+	var entries []Entry
+	var fields map[string]string
+	line := 0
+	for _, t := range tokens {
+		switch {
+		case t.tag == "STMTTRN":
+			fields, line = map[string]string{}, t.line
+		case t.tag == "/STMTTRN" && fields != nil:
+			tr, err := p.transaction(fields, userID, line)
+			entries = append(entries, Entry{Line: line, Transaction: tr, Err: err})
+			fields = nil
+		case fields != nil && !strings.HasPrefix(t.tag, "/"):
+			if _, ok := fields[t.tag]; !ok {
+				fields[t.tag] = t.text
+			}
+		}
+	}
+	return entries, nil
+}
+
+// transaction maps the fields of a STMTTRN aggregate to a transaction.
+func (p *OFXParser) transaction(fields map[string]string, userID string, line int) (db.Transaction, error) {
+	date, err := parseOFXDate(fields["DTPOSTED"])
+	if err != nil {
+		return db.Transaction{}, err
+	}
+
+	// amounts are signed and have no thousands separators, but may use a decimal comma
+	amount, err := Profile{}.ParseAmount(strings.Replace(fields["TRNAMT"], ",", ".", 1))
+	if err != nil {
+		return db.Transaction{}, err
+	}
+	operation := db.OperationCredit
+	if amount < 0 {
+		operation = db.OperationDebit
+	}
+
+	description := fields["MEMO"]
+	if description == "" {
+		description = fields["NAME"]
+	}
+
+	tr := db.Transaction{
+		UserID:        userID,
+		Timestamp:     entryDate(date),
+		Origin:        p.origin,
+		OperationType: operation,
+		Amount:        math.Abs(amount),
+		Description:   description,
+		Counterparty:  fields["NAME"],
+		ExternalID:    fields["FITID"],
+	}
+	if check := fields["CHECKNUM"]; check != "" {
+		tr.Metadata = map[string]string{"check_number": check}
+	}
+	return tr, nil
+}
+
+// parseOFXDate parses the date part of an OFX date.
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < len(ofxDateLayout) {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	date, err := time.Parse(ofxDateLayout, s[:len(ofxDateLayout)])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: %w", s, err)
+	}
+	return date, nil
+}
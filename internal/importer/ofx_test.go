@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"os"
+	"testing"
+
+	"transactions/internal/db"
+)
+
+func parseFile(t *testing.T, p Parser, path string) []Entry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	entries, err := p.Parse(f, "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestOFXParser_ParseSGML(t *testing.T) {
+	entries := parseFile(t, NewOFXParser(""), "testdata/statement.ofx")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	coffee := entries[0]
+	if coffee.Err != nil || coffee.Line != 39 {
+		t.Fatalf("unexpected entry %+v", coffee)
+	}
+	tr := coffee.Transaction
+	if tr.UserID != "john" || tr.Origin != Origin || tr.OperationType != db.OperationDebit ||
+		tr.Amount != 3.5 || tr.ExternalID != "2024010201" || tr.Counterparty != "BLUE BOTTLE" ||
+		tr.Description != "Coffee & cake" || tr.Timestamp != "2024-01-02T00:00:00Z" {
+		t.Errorf("unexpected transaction %+v", tr)
+	}
+
+	check := entries[1].Transaction
+	if check.Description != "LANDLORD" || check.Metadata["check_number"] != "1001" || check.Amount != 800 {
+		t.Errorf("unexpected transaction %+v", check)
+	}
+
+	if entries[2].Err == nil {
+		t.Errorf("expected invalid date error, got %+v", entries[2])
+	}
+}
+
+func TestOFXParser_ParseXML(t *testing.T) {
+	entries := parseFile(t, NewOFXParser("bank"), "testdata/statement.qfx")
+	if len(entries) != 1 || entries[0].Err != nil {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	tr := entries[0].Transaction
+	if tr.Origin != "bank" || tr.OperationType != db.OperationCredit || tr.Amount != 1500 ||
+		tr.ExternalID != "X-1" || tr.Counterparty != "ACME Corp." || tr.Description != "Salary" ||
+		tr.Timestamp[:10] != "2024-01-10" {
+		t.Errorf("unexpected transaction %+v", tr)
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	if got := toUTF8([]byte("Caf\xe9")); got != "Café" {
+		t.Errorf("unexpected Latin-1 decoding %q", got)
+	}
+	if got := toUTF8([]byte("Café")); got != "Café" {
+		t.Errorf("unexpected UTF-8 decoding %q", got)
+	}
+}
@@ -0,0 +1,159 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"transactions/internal/db"
+)
+
+// qifDateLayouts are the layouts of QIF dates by separator. Dates with
+// slashes are month first as written by US versions of Quicken, dates
+// with dots are day first. Years may have 2 or 4 digits.
+var qifDateLayouts = map[string][]string{
+	"/": {"1/2/2006", "1/2/06"},
+	".": {"2.1.2006", "2.1.06"},
+	"-": {"2006-01-02", "1-2-2006", "1-2-06"},
+}
+
+// QIFParser parses QIF statements of bank, cash and credit card accounts.
+type QIFParser struct {
+	origin string
+}
+
+// NewQIFParser returns a parser of QIF account records. Every record ending
+// with ^ becomes a transaction of the origin, which is `import` if empty.
+func NewQIFParser(origin string) *QIFParser {
+	if origin == "" {
+		origin = Origin
+	}
+	return &QIFParser{origin: origin}
+}
+
+// Parse parses the records of the statement. Records of investment
+// accounts, account lists and categories are skipped, as are splits.
+func (p *QIFParser) Parse(r io.Reader, userID string) ([]Entry, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	var fields map[byte]string
+	skip := false
+	start := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(toUTF8(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		switch text[0] {
+		case '!':
+			header := strings.ToLower(strings.TrimSpace(text))
+			skip = !strings.HasPrefix(header, "!type:bank") &&
+				!strings.HasPrefix(header, "!type:cash") &&
+				!strings.HasPrefix(header, "!type:ccard") &&
+				!strings.HasPrefix(header, "!type:oth")
+			fields = nil
+		case '^':
+			if fields != nil && !skip {
+				tr, err := p.transaction(fields, userID, start)
+				entries = append(entries, Entry{Line: start, Transaction: tr, Err: err})
+			}
+			fields = nil
+		default:
+			if fields == nil {
+				fields, start = map[byte]string{}, line
+			}
+			if _, ok := fields[text[0]]; !ok {
+				fields[text[0]] = strings.TrimSpace(text[1:])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if fields != nil && !skip {
+		// the last record may miss its end line
+		tr, err := p.transaction(fields, userID, start)
+		entries = append(entries, Entry{Line: start, Transaction: tr, Err: err})
+	}
+	return entries, nil
+}
+
+// transaction maps the fields of a record to a transaction.
+func (p *QIFParser) transaction(fields map[byte]string, userID string, line int) (db.Transaction, error) {
+	date, err := parseQIFDate(fields['D'])
+	if err != nil {
+		return db.Transaction{}, err
+	}
+
+	total := fields['T']
+	if total == "" {
+		total = fields['U']
+	}
+	if total == "" {
+		return db.Transaction{}, errors.New("missing amount")
+	}
+	amount, err := Profile{ThousandsSeparator: ","}.ParseAmount(total)
+	if err != nil {
+		return db.Transaction{}, err
+	}
+	operation := db.OperationCredit
+	if amount < 0 {
+		operation = db.OperationDebit
+	}
+
+	description := fields['M']
+	if description == "" {
+		description = fields['P']
+	}
+
+	tr := db.Transaction{
+		UserID:        userID,
+		Timestamp:     entryDate(date),
+		Origin:        p.origin,
+		OperationType: operation,
+		Amount:        math.Abs(amount),
+		Description:   description,
+		Counterparty:  fields['P'],
+	}
+
+	metadata := map[string]string{}
+	if check := fields['N']; check != "" {
+		metadata["check_number"] = check
+	}
+	if category := fields['L']; category != "" {
+		metadata["category"] = category
+	}
+	if len(metadata) > 0 {
+		tr.Metadata = metadata
+	}
+	return tr, nil
+}
+
+// parseQIFDate parses a QIF date, where years after 1999 may be written
+// after an apostrophe, e.g. 1/2'24.
+func parseQIFDate(s string) (time.Time, error) {
+	normalized := strings.ReplaceAll(strings.ReplaceAll(s, "'", "/"), " ", "")
+	for sep, layouts := range qifDateLayouts {
+		if !strings.Contains(normalized, sep) {
+			continue
+		}
+		for _, layout := range layouts {
+			if date, err := time.Parse(layout, normalized); err == nil {
+				return date, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"testing"
+
+	"transactions/internal/db"
+)
+
+func TestQIFParser_Parse(t *testing.T) {
+	entries := parseFile(t, NewQIFParser(""), "testdata/statement.qif")
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d: %+v", len(entries), entries)
+	}
+
+	coffee := entries[0]
+	if coffee.Err != nil || coffee.Line != 6 {
+		t.Fatalf("unexpected entry %+v", coffee)
+	}
+	tr := coffee.Transaction
+	if tr.OperationType != db.OperationDebit || tr.Amount != 3.5 || tr.Counterparty != "Blue Bottle" ||
+		tr.Description != "Coffee" || tr.Metadata["category"] != "Dining" ||
+		tr.Timestamp != "2024-01-02T00:00:00Z" {
+		t.Errorf("unexpected transaction %+v", tr)
+	}
+
+	rent := entries[1].Transaction
+	if rent.Amount != 1200 || rent.Metadata["check_number"] != "1001" || rent.Description != "Landlord" {
+		t.Errorf("unexpected transaction %+v", rent)
+	}
+
+	if entries[2].Err == nil || entries[2].Line != 17 {
+		t.Errorf("expected invalid date error on line 17, got %+v", entries[2])
+	}
+
+	refund := entries[3].Transaction
+	if entries[3].Err != nil || refund.OperationType != db.OperationCredit || refund.Amount != 25 ||
+		refund.Timestamp[:10] != "2024-01-20" {
+		t.Errorf("unexpected entry %+v", entries[3])
+	}
+}
+
+func TestParseQIFDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1/2/2024", "2024-01-02"},
+		{"01/02'24", "2024-01-02"},
+		{"1/2/98", "1998-01-02"},
+		{"2.1.2024", "2024-01-02"},
+		{"2024-01-02", "2024-01-02"},
+	}
+	for _, tt := range tests {
+		got, err := parseQIFDate(tt.in)
+		if err != nil {
+			t.Errorf("parseQIFDate(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got.Format("2006-01-02") != tt.want {
+			t.Errorf("parseQIFDate(%q) = %s, want %s", tt.in, got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
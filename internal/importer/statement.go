@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"io"
+	"math"
+
+	"transactions/internal/db"
+)
+
+// dateLayout is the layout of booking and value dates.
+const dateLayout = "2006-01-02"
+
+// Balance is a balance reported by a statement.
+type Balance struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency,omitempty"`
+	Date     string  `json:"date,omitempty"`
+}
+
+// Statement is a statement of an account with its balances.
+type Statement struct {
+	Account  string
+	Currency string
+	Opening  *Balance
+	Closing  *Balance
+	Entries  []Entry
+}
+
+// StatementParser parses documents made of statements reporting balances.
+type StatementParser interface {
+	Parser
+	ParseStatements(r io.Reader, userID string) ([]Statement, error)
+}
+
+// BalanceCheck compares the closing balance reported by a statement with the
+// balance computed from its opening balance and its transactions.
+type BalanceCheck struct {
+	Account  string   `json:"account,omitempty"`
+	Opening  *Balance `json:"opening,omitempty"`
+	Closing  *Balance `json:"closing,omitempty"`
+	Computed float64  `json:"computed"`
+	// Difference is the closing balance minus the computed balance,
+	// non zero when transactions are missing from the statement.
+	Difference float64 `json:"difference"`
+}
+
+// Check returns the balance check of the statement. Entries that
+// failed to parse are left out of the computed balance.
+func (s Statement) Check() BalanceCheck {
+	check := BalanceCheck{Account: s.Account, Opening: s.Opening, Closing: s.Closing}
+	if s.Opening != nil {
+		check.Computed = s.Opening.Amount
+	}
+	for _, e := range s.Entries {
+		if e.Err == nil {
+			check.Computed += e.Transaction.SignedAmount()
+		}
+	}
+	check.Computed = roundCents(check.Computed)
+	if s.Closing != nil {
+		check.Difference = roundCents(s.Closing.Amount - check.Computed)
+	}
+	return check
+}
+
+// Parse parses the entries of a statement with the parser, and the balance
+// checks of the statements of the document for statement parsers.
+func Parse(p Parser, r io.Reader, userID string) ([]Entry, []BalanceCheck, error) {
+	sp, ok := p.(StatementParser)
+	if !ok {
+		entries, err := p.Parse(r, userID)
+		return entries, nil, err
+	}
+
+	statements, err := sp.ParseStatements(r, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []Entry
+	checks := make([]BalanceCheck, 0, len(statements))
+	for _, s := range statements {
+		entries = append(entries, s.Entries...)
+		checks = append(checks, s.Check())
+	}
+	return entries, checks, nil
+}
+
+// entries returns the entries of the statements.
+func entries(statements []Statement) []Entry {
+	var all []Entry
+	for _, s := range statements {
+		all = append(all, s.Entries...)
+	}
+	return all
+}
+
+// roundCents rounds an amount to cents.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// signed returns the amount negated for debits.
+func signed(amount float64, debit bool) float64 {
+	if debit {
+		return -amount
+	}
+	return amount
+}
+
+// operation returns the operation type of a signed amount.
+func operation(amount float64) string {
+	if amount < 0 {
+		return db.OperationDebit
+	}
+	return db.OperationCredit
+}
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"transactions/internal/db"
+)
+
+func parseStatementsFile(t *testing.T, p StatementParser, path string) []Statement {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	statements, err := p.ParseStatements(f, "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statements
+}
+
+func TestCamtParser_ParseStatements(t *testing.T) {
+	statements := parseStatementsFile(t, NewCamtParser(""), "testdata/statement.camt.xml")
+	if len(statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(statements))
+	}
+
+	stmt := statements[0]
+	if stmt.Account != "DE89370400440532013000" || stmt.Currency != "EUR" ||
+		stmt.Opening == nil || stmt.Opening.Amount != 1000 || stmt.Closing == nil || stmt.Closing.Amount != 2400 {
+		t.Fatalf("unexpected statement %+v", stmt)
+	}
+	if len(stmt.Entries) != 2 {
+		t.Fatalf("expected 2 entries without the information entry, got %d", len(stmt.Entries))
+	}
+
+	rent := stmt.Entries[0]
+	if rent.Err != nil || rent.Line != 26 {
+		t.Fatalf("unexpected entry %+v", rent)
+	}
+	tr := rent.Transaction
+	if tr.OperationType != db.OperationDebit || tr.Amount != 100 || tr.Counterparty != "Landlord Ltd" ||
+		tr.Reference != "E2E-1" || tr.ExternalID != "REF-1" || tr.Description != "Rent January" ||
+		tr.BookingDate != "2024-01-02" || tr.ValueDate != "2024-01-03" || tr.Timestamp[:10] != "2024-01-02" {
+		t.Errorf("unexpected transaction %+v", tr)
+	}
+
+	salary := stmt.Entries[1].Transaction
+	if salary.OperationType != db.OperationCredit || salary.Counterparty != "ACME Corp." ||
+		salary.Reference != "" || salary.ExternalID != "REF-2" || salary.Description != "Salary" ||
+		salary.Status != db.StatusPending || salary.BookingDate != "2024-01-05" {
+		t.Errorf("unexpected transaction %+v", salary)
+	}
+
+	check := stmt.Check()
+	if check.Computed != 2400 || check.Difference != 0 {
+		t.Errorf("unexpected balance check %+v", check)
+	}
+}
+
+func TestMT940Parser_ParseStatements(t *testing.T) {
+	statements := parseStatementsFile(t, NewMT940Parser(""), "testdata/statement.mt940")
+	if len(statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(statements))
+	}
+
+	stmt := statements[0]
+	if stmt.Account != "DE89370400440532013000" || stmt.Currency != "EUR" ||
+		stmt.Opening.Amount != 1000 || stmt.Opening.Date != "2023-12-31" || stmt.Closing.Amount != 2495.5 {
+		t.Fatalf("unexpected statement %+v", stmt)
+	}
+	if len(stmt.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(stmt.Entries))
+	}
+
+	coffee := stmt.Entries[0]
+	if coffee.Err != nil || coffee.Line != 6 {
+		t.Fatalf("unexpected entry %+v", coffee)
+	}
+	tr := coffee.Transaction
+	if tr.OperationType != db.OperationDebit || tr.Amount != 3.5 || tr.Counterparty != "BLUE BOTTLE BERLIN" ||
+		tr.Description != "EREF+E2E-1 Coffee and cake" || tr.Reference != "" || tr.ExternalID != "B4A02" ||
+		tr.BookingDate != "2024-01-02" || tr.ValueDate != "2024-01-02" {
+		t.Errorf("unexpected transaction %+v", tr)
+	}
+
+	salary := stmt.Entries[1].Transaction
+	if salary.OperationType != db.OperationCredit || salary.Amount != 1500 || salary.Counterparty != "ACME Corp." ||
+		salary.Description != "Salary January" || salary.Reference != "SALARY-JAN" {
+		t.Errorf("unexpected transaction %+v", salary)
+	}
+
+	fee := stmt.Entries[2].Transaction
+	if fee.Description != "Account fee" || fee.ExternalID != "" || fee.BookingDate != "2024-01-31" {
+		t.Errorf("unexpected transaction %+v", fee)
+	}
+
+	check := stmt.Check()
+	if check.Computed != 2495.5 || check.Difference != 0 {
+		t.Errorf("unexpected balance check %+v", check)
+	}
+}
+
+func TestMT940EntryDate(t *testing.T) {
+	value, _ := parseMT940Date("240102")
+	date, err := mt940EntryDate(value, "1231")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if date.Format(dateLayout) != "2023-12-31" {
+		t.Errorf("expected entry date in the previous year, got %s", date.Format(dateLayout))
+	}
+}
+
+func TestParse_BalanceChecks(t *testing.T) {
+	f, err := os.Open("testdata/statement.mt940")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	entries, checks, err := Parse(NewMT940Parser(""), f, "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || len(checks) != 1 || checks[0].Closing.Amount != 2495.5 {
+		t.Errorf("unexpected entries %d and checks %+v", len(entries), checks)
+	}
+}
+
+func TestStatement_CheckDifference(t *testing.T) {
+	stmt := Statement{
+		Opening: &Balance{Amount: 10},
+		Closing: &Balance{Amount: 5},
+		Entries: []Entry{
+			{Transaction: db.Transaction{OperationType: db.OperationDebit, Amount: 2.1}},
+			{Transaction: db.Transaction{OperationType: db.OperationDebit, Amount: 100}, Err: errors.New("invalid date")},
+		},
+	}
+	check := stmt.Check()
+	if check.Computed != 7.9 || check.Difference != -2.9 {
+		t.Errorf("unexpected balance check %+v", check)
+	}
+}
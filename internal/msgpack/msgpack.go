@@ -0,0 +1,177 @@
+// Package msgpack encodes values in the MessagePack format.
+//
+// Values are encoded as they are encoded in JSON, following their json struct
+// tags: they are marshalled to JSON and the JSON values are encoded as MessagePack
+// values. Map keys are sorted for the encoding of a value to be stable, and
+// numbers are encoded as integers when they are integers, as floats otherwise.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ContentType is the media type of MessagePack.
+const ContentType = "application/msgpack"
+
+// Marshal returns the MessagePack encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encode writes the encoding of a decoded JSON value.
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		return encodeNumber(buf, v)
+	case string:
+		encodeString(buf, v)
+	case []interface{}:
+		encodeLength(buf, len(v), 0x90, 0xdc, 0xdd, 15)
+		for _, e := range v {
+			if err := encode(buf, e); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		encodeLength(buf, len(v), 0x80, 0xde, 0xdf, 15)
+		for _, k := range keys {
+			encodeString(buf, k)
+			if err := encode(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported value of type %T", v)
+	}
+	return nil
+}
+
+// encodeNumber writes a number as the smallest integer holding it,
+// or as a 64-bit float.
+func encodeNumber(buf *bytes.Buffer, n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		encodeInt(buf, i)
+		return nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		buf.WriteByte(0xcf)
+		writeUint(buf, u, 8)
+		return nil
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return fmt.Errorf("msgpack: invalid number %s: %w", n, err)
+	}
+	buf.WriteByte(0xcb)
+	writeUint(buf, math.Float64bits(f), 8)
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		buf.WriteByte(byte(i)) // positive fixint
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(i)) // negative fixint
+	case i >= 0 && i <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		writeUint(buf, uint64(i), 1)
+	case i >= 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		writeUint(buf, uint64(i), 2)
+	case i >= 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		writeUint(buf, uint64(i), 4)
+	case i >= 0:
+		buf.WriteByte(0xcf)
+		writeUint(buf, uint64(i), 8)
+	case i >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		writeUint(buf, uint64(i), 1)
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		writeUint(buf, uint64(i), 2)
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		writeUint(buf, uint64(i), 4)
+	default:
+		buf.WriteByte(0xd3)
+		writeUint(buf, uint64(i), 8)
+	}
+}
+
+func encodeString(buf *bytes.Buffer, s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		buf.WriteByte(0xa0 | byte(n)) // fixstr
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		writeUint(buf, uint64(n), 1)
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		writeUint(buf, uint64(n), 2)
+	default:
+		buf.WriteByte(0xdb)
+		writeUint(buf, uint64(n), 4)
+	}
+	buf.WriteString(s)
+}
+
+// encodeLength writes the header of an array or a map of n elements:
+// the fix type holding lengths up to fixMax, or the 16 or 32-bit type.
+func encodeLength(buf *bytes.Buffer, n int, fix, type16, type32 byte, fixMax int) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(type16)
+		writeUint(buf, uint64(n), 2)
+	default:
+		buf.WriteByte(type32)
+		writeUint(buf, uint64(n), 4)
+	}
+}
+
+// writeUint writes the size low bytes of u in big-endian order.
+func writeUint(buf *bytes.Buffer, u uint64, size int) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], u)
+	buf.Write(b[8-size:])
+}
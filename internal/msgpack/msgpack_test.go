@@ -0,0 +1,82 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want string // hex
+	}{
+		{"nil", nil, "c0"},
+		{"true", true, "c3"},
+		{"false", false, "c2"},
+		{"positive fixint", 1, "01"},
+		{"negative fixint", -1, "ff"},
+		{"uint8", 200, "ccc8"},
+		{"uint16", 1000, "cd03e8"},
+		{"uint32", 70000, "ce00011170"},
+		{"uint64", uint64(1) << 40, "cf0000010000000000"},
+		{"int8", -100, "d09c"},
+		{"int16", -1000, "d1fc18"},
+		{"int32", -70000, "d2fffeee90"},
+		{"float", 12.5, "cb4029000000000000"},
+		{"fixstr", "web", "a3776562"},
+		{"fixarray", []int{1, 2}, "920102"},
+		{"fixmap", map[string]int{"b": 2, "a": 1}, "82a16101a16202"},
+		{
+			"struct",
+			struct {
+				Amount float64 `json:"amount"`
+				Note   string  `json:"note,omitempty"`
+				Tags   []string
+			}{Amount: 1.5},
+			// a nil slice is null, as in JSON
+			"82a454616773c0a6616d6f756e74cb3ff8000000000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("Marshal(%v) = %x, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshal_Lengths(t *testing.T) {
+	s := strings.Repeat("a", 40)
+	got, _ := Marshal(s)
+	if !bytes.HasPrefix(got, []byte{0xd9, 40}) || len(got) != 42 {
+		t.Errorf("unexpected str8 encoding %x", got[:2])
+	}
+
+	s = strings.Repeat("a", 300)
+	got, _ = Marshal(s)
+	if !bytes.HasPrefix(got, []byte{0xda, 0x01, 0x2c}) {
+		t.Errorf("unexpected str16 encoding %x", got[:3])
+	}
+
+	got, _ = Marshal(make([]bool, 16))
+	if !bytes.HasPrefix(got, []byte{0xdc, 0x00, 0x10}) || len(got) != 19 {
+		t.Errorf("unexpected array16 encoding %x", got[:3])
+	}
+
+	m := make(map[string]bool, 16)
+	for i := 0; i < 16; i++ {
+		m[string(rune('a'+i))] = true
+	}
+	got, _ = Marshal(m)
+	if !bytes.HasPrefix(got, []byte{0xde, 0x00, 0x10}) {
+		t.Errorf("unexpected map16 encoding %x", got[:3])
+	}
+}
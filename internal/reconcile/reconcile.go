@@ -0,0 +1,328 @@
+// Package reconcile reconciles bank statements with the stored transactions of a user.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"transactions/internal/db"
+	"transactions/internal/importer"
+)
+
+// dateLayout is the layout of the dates transactions are matched by.
+const dateLayout = "2006-01-02"
+
+// Default tolerances of fuzzy matches
+const (
+	DefaultAmountTolerance = 0.01
+	// DefaultDateTolerance is in days.
+	DefaultDateTolerance = 3
+)
+
+var (
+	// ErrInvalidRange is returned for ranges that are not dates or end before they start.
+	ErrInvalidRange = errors.New("invalid reconciliation range")
+	// ErrEmptyStatement is returned when the range cannot be taken from the statement.
+	ErrEmptyStatement = errors.New("statement has no transactions")
+)
+
+// Options are the tolerances of fuzzy matches.
+type Options struct {
+	// AmountTolerance is the largest difference of the amounts of a fuzzy match.
+	AmountTolerance float64 `json:"amount_tolerance,omitempty"`
+	// DateTolerance is the largest number of days between the dates of a fuzzy match.
+	DateTolerance int `json:"date_tolerance,omitempty"`
+}
+
+// withDefaults returns the options with the default tolerances in place of zero values.
+func (o Options) withDefaults() Options {
+	if o.AmountTolerance <= 0 {
+		o.AmountTolerance = DefaultAmountTolerance
+	}
+	if o.DateTolerance <= 0 {
+		o.DateTolerance = DefaultDateTolerance
+	}
+	return o
+}
+
+// Item is a statement line without a stored transaction.
+type Item struct {
+	Line        int            `json:"line"`
+	Transaction db.Transaction `json:"transaction"`
+}
+
+// Match is a statement line matched with a stored transaction.
+type Match struct {
+	Line        int            `json:"line"`
+	Statement   db.Transaction `json:"statement"`
+	Transaction db.Transaction `json:"transaction"`
+	// Exact reports whether the amounts and dates are equal, or the external IDs
+	// and amounts are; fuzzy matches are within the tolerances.
+	Exact bool `json:"exact"`
+	// AmountDifference is the signed amount of the statement line minus the
+	// one of the transaction, DateDifference the days between their dates.
+	AmountDifference float64 `json:"amount_difference,omitempty"`
+	DateDifference   int     `json:"date_difference,omitempty"`
+}
+
+// Report reports the result of a reconciliation.
+type Report struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Matched []Match `json:"matched"`
+	// Missing are the statement lines without a stored transaction.
+	Missing []Item `json:"missing"`
+	// Unexpected are the stored transactions of the range without a statement line.
+	Unexpected []db.Transaction `json:"unexpected"`
+	// Errors are the statement lines that failed to parse.
+	Errors []importer.RowError `json:"errors,omitempty"`
+	// StatementTotal is the sum of the signed amounts of the statement lines, LedgerTotal
+	// the one of the matched and unexpected transactions and Difference the first minus the latter.
+	StatementTotal float64 `json:"statement_total"`
+	LedgerTotal    float64 `json:"ledger_total"`
+	Difference     float64 `json:"difference"`
+	// Balances are the balance checks of statements reporting balances.
+	Balances []importer.BalanceCheck `json:"balances,omitempty"`
+}
+
+// Reconciled reports whether every statement line matched a transaction
+// and every transaction a statement line, with no difference of the totals.
+func (r Report) Reconciled() bool {
+	return len(r.Missing) == 0 && len(r.Unexpected) == 0 && len(r.Errors) == 0 && r.Difference == 0
+}
+
+// Lister lists the stored transactions of a user.
+type Lister interface {
+	ForEach(ctx context.Context, req db.UserListRequest, fn func(db.Transaction) error) error
+}
+
+// Reconciler reconciles statements with the transactions of the lister.
+type Reconciler struct {
+	Lister  Lister
+	Options Options
+}
+
+// Reconcile reconciles the entries of a statement of a user with the
+// transactions stored between the from and to dates, the dates of the first
+// and last statement lines when empty. Transactions up to the date tolerance
+// outside of the range are matched as well, but are not unexpected.
+func (r Reconciler) Reconcile(
+	ctx context.Context,
+	userID, from, to string,
+	entries []importer.Entry,
+) (Report, error) {
+	opts := r.Options.withDefaults()
+
+	first, last := statementRange(entries)
+	if from == "" {
+		from = first
+	}
+	if to == "" {
+		to = last
+	}
+	if from == "" || to == "" {
+		return Report{}, ErrEmptyStatement
+	}
+	start, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: %v", ErrInvalidRange, err)
+	}
+	end, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: %v", ErrInvalidRange, err)
+	}
+	if end.Before(start) {
+		return Report{}, fmt.Errorf("%w: %s is after %s", ErrInvalidRange, from, to)
+	}
+
+	tolerance := time.Duration(opts.DateTolerance) * 24 * time.Hour
+	req := db.UserListRequest{
+		UserID: userID,
+		From:   start.Add(-tolerance).Format(dateLayout),
+		To:     end.Add(tolerance).Format(dateLayout),
+	}
+	var ledger []db.Transaction
+	err = r.Lister.ForEach(ctx, req, func(t db.Transaction) error {
+		// failed and voided transactions never reach the bank
+		if t.Status != db.StatusFailed && t.Status != db.StatusVoided {
+			ledger = append(ledger, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	return Compare(entries, ledger, opts, from, to), nil
+}
+
+// statementRange returns the dates of the first and last statement lines.
+func statementRange(entries []importer.Entry) (first, last string) {
+	for _, e := range entries {
+		if e.Err != nil {
+			continue
+		}
+		d := date(e.Transaction)
+		if first == "" || d < first {
+			first = d
+		}
+		if d > last {
+			last = d
+		}
+	}
+	return first, last
+}
+
+// Compare matches the statement entries with the ledger transactions, first the
+// exact matches then the fuzzy ones within the tolerances of the options. Unmatched
+// transactions are unexpected when their date is between from and to.
+func Compare(entries []importer.Entry, ledger []db.Transaction, opts Options, from, to string) Report {
+	opts = opts.withDefaults()
+	report := Report{
+		From:       from,
+		To:         to,
+		Matched:    []Match{},
+		Missing:    []Item{},
+		Unexpected: []db.Transaction{},
+	}
+
+	lines := make([]importer.Entry, 0, len(entries))
+	for _, e := range entries {
+		if e.Err != nil {
+			report.Errors = append(report.Errors, importer.RowError{Line: e.Line, Error: e.Err.Error()})
+			continue
+		}
+		lines = append(lines, e)
+		report.StatementTotal += e.Transaction.SignedAmount()
+	}
+
+	matched := make([]*Match, len(lines))
+	used := make([]bool, len(ledger))
+	match := func(i, j int, exact bool) {
+		s, t := lines[i].Transaction, ledger[j]
+		m := Match{
+			Line:             lines[i].Line,
+			Statement:        s,
+			Transaction:      t,
+			AmountDifference: cents(s.SignedAmount() - t.SignedAmount()),
+			DateDifference:   days(date(s), date(t)),
+			Exact:            exact,
+		}
+		matched[i], used[j] = &m, true
+	}
+
+	// exact matches: same external ID and amount, then same date and amount
+	exact := []func(s, t db.Transaction) bool{
+		func(s, t db.Transaction) bool { return s.ExternalID != "" && s.ExternalID == t.ExternalID },
+		func(s, t db.Transaction) bool { return date(s) == date(t) },
+	}
+	for _, same := range exact {
+		for i, e := range lines {
+			if matched[i] != nil {
+				continue
+			}
+			for j, t := range ledger {
+				if !used[j] && cents(e.Transaction.SignedAmount()) == cents(t.SignedAmount()) && same(e.Transaction, t) {
+					match(i, j, true)
+					break
+				}
+			}
+		}
+	}
+
+	// fuzzy matches: the closest transaction in time, then in amount, within the tolerances
+	for i, e := range lines {
+		if matched[i] != nil {
+			continue
+		}
+		best, bestDays, bestAmount := -1, 0, 0.0
+		for j, t := range ledger {
+			if used[j] {
+				continue
+			}
+			d := abs(days(date(e.Transaction), date(t)))
+			a := math.Abs(cents(e.Transaction.SignedAmount() - t.SignedAmount()))
+			if d > opts.DateTolerance || a > opts.AmountTolerance+1e-9 {
+				continue
+			}
+			if best < 0 || d < bestDays || d == bestDays && a < bestAmount {
+				best, bestDays, bestAmount = j, d, a
+			}
+		}
+		if best >= 0 {
+			match(i, best, false)
+		}
+	}
+
+	for i, m := range matched {
+		if m == nil {
+			report.Missing = append(report.Missing, Item{Line: lines[i].Line, Transaction: lines[i].Transaction})
+			continue
+		}
+		report.Matched = append(report.Matched, *m)
+		report.LedgerTotal += m.Transaction.SignedAmount()
+	}
+	for j, t := range ledger {
+		if used[j] {
+			continue
+		}
+		if d := date(t); d >= from && d <= to {
+			report.Unexpected = append(report.Unexpected, t)
+			report.LedgerTotal += t.SignedAmount()
+		}
+	}
+
+	report.StatementTotal = cents(report.StatementTotal)
+	report.LedgerTotal = cents(report.LedgerTotal)
+	report.Difference = cents(report.StatementTotal - report.LedgerTotal)
+	return report
+}
+
+// date returns the date of a transaction, its booking date if any.
+func date(t db.Transaction) string {
+	if t.BookingDate != "" {
+		return t.BookingDate
+	}
+	if len(t.Timestamp) < len(dateLayout) {
+		return t.Timestamp
+	}
+	return t.Timestamp[:len(dateLayout)]
+}
+
+// days returns the number of days from the second date to the first one,
+// 0 when either is not a date.
+func days(a, b string) int {
+	ta, err := time.Parse(dateLayout, a)
+	if err != nil {
+		return 0
+	}
+	tb, err := time.Parse(dateLayout, b)
+	if err != nil {
+		return 0
+	}
+	return int(ta.Sub(tb).Hours() / 24)
+}
+
+// cents rounds an amount to cents.
+func cents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Request is a request to reconcile a statement of a user with the transactions
+// stored between the from and to dates. The DryRun of the statement is ignored.
+type Request struct {
+	importer.Request
+	Options
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
@@ -0,0 +1,160 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"transactions/internal/db"
+	"transactions/internal/importer"
+)
+
+func transaction(ts string, amount float64, externalID string) db.Transaction {
+	op := db.OperationCredit
+	if amount < 0 {
+		op, amount = db.OperationDebit, -amount
+	}
+	return db.Transaction{
+		UserID:        "john",
+		Timestamp:     ts,
+		Origin:        "web",
+		OperationType: op,
+		Amount:        amount,
+		Status:        db.StatusPosted,
+		ExternalID:    externalID,
+	}
+}
+
+func line(n int, date string, amount float64, externalID string) importer.Entry {
+	return importer.Entry{Line: n, Transaction: transaction(date+"T00:00:00Z", amount, externalID)}
+}
+
+func TestCompare(t *testing.T) {
+	entries := []importer.Entry{
+		line(1, "2024-01-02", -10, "FIT-1"),
+		line(2, "2024-01-03", -25.5, ""),
+		line(3, "2024-01-05", -12, ""),
+		line(4, "2024-01-06", 100, ""),
+		{Line: 5, Err: errors.New("invalid date")},
+		line(6, "2024-01-20", -7, ""),
+	}
+	ledger := []db.Transaction{
+		transaction("2024-01-04T09:00:00Z", -10, "FIT-1"),
+		transaction("2024-01-03T10:00:00Z", -25.5, ""),
+		transaction("2024-01-03T12:00:00Z", -25.5, ""),
+		transaction("2024-01-07T08:00:00Z", -12.01, ""),
+		transaction("2024-01-10T08:00:00Z", 100, ""),
+		transaction("2024-01-31T08:00:00Z", -3, ""),
+	}
+
+	report := Compare(entries, ledger, Options{}, "2024-01-01", "2024-01-20")
+
+	want := []struct {
+		line   int
+		ts     string
+		exact  bool
+		amount float64
+		days   int
+	}{
+		{1, "2024-01-04T09:00:00Z", true, 0, -2},
+		{2, "2024-01-03T10:00:00Z", true, 0, 0},
+		{3, "2024-01-07T08:00:00Z", false, 0.01, -2},
+	}
+	if len(report.Matched) != len(want) {
+		t.Fatalf("expected %d matches, got %+v", len(want), report.Matched)
+	}
+	for i, w := range want {
+		m := report.Matched[i]
+		if m.Line != w.line || m.Transaction.Timestamp != w.ts || m.Exact != w.exact ||
+			m.AmountDifference != w.amount || m.DateDifference != w.days {
+			t.Errorf("expected match %+v, got line %d, ts %s, exact %v, amount %v, days %d",
+				w, m.Line, m.Transaction.Timestamp, m.Exact, m.AmountDifference, m.DateDifference)
+		}
+	}
+
+	if len(report.Missing) != 2 || report.Missing[0].Line != 4 || report.Missing[1].Line != 6 {
+		t.Errorf("unexpected missing lines %+v", report.Missing)
+	}
+	// the transaction after the range is not unexpected
+	if len(report.Unexpected) != 2 ||
+		report.Unexpected[0].Timestamp != "2024-01-03T12:00:00Z" ||
+		report.Unexpected[1].Timestamp != "2024-01-10T08:00:00Z" {
+		t.Errorf("unexpected transactions %+v", report.Unexpected)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Line != 5 {
+		t.Errorf("unexpected errors %+v", report.Errors)
+	}
+	if report.StatementTotal != 45.5 || report.LedgerTotal != 26.99 || report.Difference != 18.51 {
+		t.Errorf("unexpected totals %v, %v, %v", report.StatementTotal, report.LedgerTotal, report.Difference)
+	}
+	if report.Reconciled() {
+		t.Errorf("expected report not to be reconciled")
+	}
+}
+
+func TestCompareReconciled(t *testing.T) {
+	entries := []importer.Entry{line(1, "2024-01-02", -10, ""), line(2, "2024-01-02", -10, "")}
+	ledger := []db.Transaction{
+		transaction("2024-01-02T00:00:00.000002Z", -10, ""),
+		transaction("2024-01-02T00:00:00.000001Z", -10, ""),
+	}
+
+	report := Compare(entries, ledger, Options{}, "2024-01-02", "2024-01-02")
+	if !report.Reconciled() || len(report.Matched) != 2 {
+		t.Errorf("expected reconciled report, got %+v", report)
+	}
+}
+
+// lister lists the transactions matching the timestamp range of the request.
+type lister struct {
+	transactions []db.Transaction
+	req          db.UserListRequest
+}
+
+func (l *lister) ForEach(ctx context.Context, req db.UserListRequest, fn func(db.Transaction) error) error {
+	l.req = req
+	for _, t := range l.transactions {
+		if t.Timestamp >= req.From && t.Timestamp[:10] <= req.To {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	voided := transaction("2024-01-02T10:00:00Z", -5, "")
+	voided.Status = db.StatusVoided
+	l := &lister{transactions: []db.Transaction{
+		transaction("2024-01-01T10:00:00Z", -10, ""),
+		voided,
+		transaction("2024-01-09T10:00:00Z", -3, ""),
+	}}
+	r := Reconciler{Lister: l, Options: Options{DateTolerance: 1}}
+
+	entries := []importer.Entry{line(1, "2024-01-02", -10, ""), line(2, "2024-01-03", -5, "")}
+	report, err := r.Reconcile(context.Background(), "john", "", "", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.req.UserID != "john" || l.req.From != "2024-01-01" || l.req.To != "2024-01-04" {
+		t.Errorf("unexpected list request %+v", l.req)
+	}
+	if report.From != "2024-01-02" || report.To != "2024-01-03" {
+		t.Errorf("expected statement range, got %s to %s", report.From, report.To)
+	}
+	if len(report.Matched) != 1 || len(report.Missing) != 1 || len(report.Unexpected) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	if _, err := r.Reconcile(context.Background(), "john", "2024-02", "", entries); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("expected ErrInvalidRange, got %v", err)
+	}
+	if _, err := r.Reconcile(context.Background(), "john", "2024-02-01", "2024-01-01", entries); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("expected ErrInvalidRange, got %v", err)
+	}
+	if _, err := r.Reconcile(context.Background(), "john", "", "", nil); !errors.Is(err, ErrEmptyStatement) {
+		t.Errorf("expected ErrEmptyStatement, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	ctx := context.Background()
+	d := NewDir(t.TempDir())
+
+	if err := d.Put(ctx, "exports/1/file.csv", strings.NewReader("a,b\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := d.Get(ctx, "exports/1/file.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "a,b\n" {
+		t.Errorf("unexpected object %q", body)
+	}
+
+	loc, err := d.Location(ctx, "exports/1/file.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(loc, "file:///") || !strings.HasSuffix(loc, "/exports/1/file.csv") {
+		t.Errorf("unexpected location %s", loc)
+	}
+
+	if err := d.Delete(ctx, "exports/1/file.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Get(ctx, "exports/1/file.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := d.Delete(ctx, "exports/1/file.csv"); err != nil {
+		t.Errorf("unexpected error deleting a missing object: %v", err)
+	}
+}
+
+func TestDir_InvalidKey(t *testing.T) {
+	d := NewDir(t.TempDir())
+	for _, key := range []string{"", "../escape", "a/../../b", "/abs"} {
+		if err := d.Put(context.Background(), key, strings.NewReader("")); err == nil {
+			t.Errorf("expected error putting key %q", key)
+		}
+	}
+}
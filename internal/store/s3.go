@@ -0,0 +1,89 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// DownloadURLExpiry is how long a download location of the S3 store is valid.
+const DownloadURLExpiry = 15 * time.Minute
+
+// S3 stores objects in an S3 bucket.
+type S3 struct {
+	c       *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+}
+
+// NewS3 returns a store of the objects in the bucket.
+func NewS3(bucket string) *S3 {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	c := s3.NewFromConfig(cfg)
+	return &S3{c: c, presign: s3.NewPresignClient(c), bucket: bucket}
+}
+
+// Put uploads the object. The object is read in memory first,
+// as uploads of unknown length are not supported.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader) error {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.c.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	})
+	return err
+}
+
+// Get downloads the object.
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.c.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// Delete deletes the object.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.c.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// Location returns a presigned download URL of the object valid for DownloadURLExpiry.
+func (s *S3) Location(ctx context.Context, key string) (string, error) {
+	req, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(DownloadURLExpiry))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
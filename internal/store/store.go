@@ -0,0 +1,40 @@
+// Package store provides an object store abstraction for files produced
+// by the service, such as exports.
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Store stores objects by key. Keys are slash separated paths.
+type Store interface {
+	// Put stores the object read from r, replacing an existing object.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the object for reading.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete deletes the object, if it exists.
+	Delete(ctx context.Context, key string) error
+	// Location returns where the object can be downloaded from.
+	Location(ctx context.Context, key string) (string, error)
+}
+
+// FromEnv returns the S3 store of the EXPORTS_BUCKET bucket, or the directory
+// store of EXPORTS_DIR (exports in the temporary directory by default)
+// when no bucket is set.
+func FromEnv() Store {
+	if bucket := os.Getenv("EXPORTS_BUCKET"); bucket != "" {
+		return NewS3(bucket)
+	}
+	dir := os.Getenv("EXPORTS_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "exports")
+	}
+	return NewDir(dir)
+}
@@ -17,6 +17,7 @@ var TransactionFactory = factory.NewFactory(
 	factory.Use("nick", "john", "james", "foo", "bar").For("UserID"),
 	factory.Use(uuid.NewString).For("ID"),
 	factory.Use("credit", "debit").For("OperationType"),
+	factory.Use("posted").For("Status"),
 	factory.Use("web", "mobile", "ios", "android", "desktop").For("Origin"),
 	factory.Use(db.Timestamp).For("Timestamp"),
 	factory.Use(amount).For("Amount"),
@@ -3,7 +3,6 @@
 # Disable cli pager
 # export AWS_PAGER=""
 
-TABLE_NAME="Transactions"
 ENDPOINT_URL="http://localhost:8000" # URL of your local DynamoDB instance
 
 # Timeout and interval in seconds
@@ -36,22 +35,76 @@ done
 
 echo "DynamoDB is up and running on port 8000."
 
-# Check if the table exists
-if aws dynamodb describe-table --no-cli-pager --table-name $TABLE_NAME --endpoint-url $ENDPOINT_URL > /dev/null 2>&1; then
-  echo "Table $TABLE_NAME already exists."
-else
-  # Create the table
-	aws dynamodb create-table \
-		--table-name Transactions \
-		--attribute-definitions \
-			AttributeName=user_id,AttributeType=S \
-			AttributeName=ts,AttributeType=S \
-		--key-schema \
-			AttributeName=user_id,KeyType=HASH \
-			AttributeName=ts,KeyType=RANGE \
-		--billing-mode PAY_PER_REQUEST \
-		--endpoint-url http://localhost:8000 \
-		--stream-specification StreamEnabled=true,StreamViewType=NEW_IMAGE
-
-  echo "Table $TABLE_NAME created."
-fi
+# Create a table unless it exists. The first argument is the table name,
+# the rest are passed to `aws dynamodb create-table`.
+create_table() {
+  local table_name=$1
+  shift
+
+  # Check if the table exists
+  if aws dynamodb describe-table --no-cli-pager --table-name $table_name --endpoint-url $ENDPOINT_URL > /dev/null 2>&1; then
+    echo "Table $table_name already exists."
+  else
+    # Create the table
+    aws dynamodb create-table \
+      --no-cli-pager \
+      --table-name $table_name \
+      --billing-mode PAY_PER_REQUEST \
+      --endpoint-url $ENDPOINT_URL \
+      "$@" > /dev/null
+
+    echo "Table $table_name created."
+  fi
+}
+
+create_table Transactions \
+  --attribute-definitions \
+    AttributeName=user_id,AttributeType=S \
+    AttributeName=ts,AttributeType=S \
+    AttributeName=user_category,AttributeType=S \
+  --key-schema \
+    AttributeName=user_id,KeyType=HASH \
+    AttributeName=ts,KeyType=RANGE \
+  --global-secondary-indexes \
+    "IndexName=user_category-ts-index,KeySchema=[{AttributeName=user_category,KeyType=HASH},{AttributeName=ts,KeyType=RANGE}],Projection={ProjectionType=ALL}" \
+  --stream-specification StreamEnabled=true,StreamViewType=NEW_AND_OLD_IMAGES
+
+create_table TransactionTags \
+  --attribute-definitions \
+    AttributeName=user_tag,AttributeType=S \
+    AttributeName=ts,AttributeType=S \
+  --key-schema \
+    AttributeName=user_tag,KeyType=HASH \
+    AttributeName=ts,KeyType=RANGE
+
+create_table Holds \
+  --attribute-definitions \
+    AttributeName=user_id,AttributeType=S \
+    AttributeName=hold_id,AttributeType=S \
+  --key-schema \
+    AttributeName=user_id,KeyType=HASH \
+    AttributeName=hold_id,KeyType=RANGE \
+  --stream-specification StreamEnabled=true,StreamViewType=OLD_IMAGE
+
+create_table Rules \
+  --attribute-definitions \
+    AttributeName=user_id,AttributeType=S \
+    AttributeName=rule_id,AttributeType=S \
+  --key-schema \
+    AttributeName=user_id,KeyType=HASH \
+    AttributeName=rule_id,KeyType=RANGE
+
+create_table SearchIndex \
+  --attribute-definitions \
+    AttributeName=user_id,AttributeType=S \
+    AttributeName=term,AttributeType=S \
+  --key-schema \
+    AttributeName=user_id,KeyType=HASH \
+    AttributeName=term,KeyType=RANGE
+
+create_table Exports \
+  --attribute-definitions \
+    AttributeName=export_id,AttributeType=S \
+  --key-schema \
+    AttributeName=export_id,KeyType=HASH \
+  --stream-specification StreamEnabled=true,StreamViewType=NEW_IMAGE